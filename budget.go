@@ -0,0 +1,82 @@
+package record
+
+import (
+	"time"
+)
+
+const (
+	dropLowConfidence = "confidence below threshold"
+	dropOverBudget    = "exceeds budget"
+)
+
+// droppedTest is a predicted test that is not selected.
+type droppedTest struct {
+	predictedTest
+	Reason string
+}
+
+// selectTests returns the predicted tests within the limits in their original
+// order, which ranks the most relevant tests first. Tests with a confidence
+// below the threshold are dropped. Then each test is selected if its
+// estimated duration fits the remaining budget, so smaller tests can fill the
+// budget after a larger test was dropped. Tests without a duration are
+// estimated with the mean duration. It also returns the estimated runtime of
+// the selection and false if the runtime cannot be estimated, since no test
+// has a duration.
+func selectTests(tests []predictedTest, budget time.Duration, confidence float64) ([]predictedTest, []droppedTest, float64, bool) {
+	var selected []predictedTest
+	var dropped []droppedTest
+
+	for _, t := range tests {
+		if confidence > 0 && t.Confidence != nil && *t.Confidence < confidence {
+			dropped = append(dropped, droppedTest{t, dropLowConfidence})
+			continue
+		}
+		selected = append(selected, t)
+	}
+
+	var known float64
+	var count int
+	for _, t := range selected {
+		if t.Duration != nil {
+			known += *t.Duration
+			count++
+		}
+	}
+
+	if count == 0 {
+		return selected, dropped, 0, false
+	}
+
+	mean := known / float64(count)
+	estimate := func(t predictedTest) float64 {
+		if t.Duration != nil {
+			return *t.Duration
+		}
+		return mean
+	}
+
+	if budget <= 0 {
+		var total float64
+		for _, t := range selected {
+			total += estimate(t)
+		}
+		return selected, dropped, total, true
+	}
+
+	limit := budget.Seconds()
+
+	var total float64
+	var within []predictedTest
+	for _, t := range selected {
+		d := estimate(t)
+		if total+d > limit {
+			dropped = append(dropped, droppedTest{t, dropOverBudget})
+			continue
+		}
+		total += d
+		within = append(within, t)
+	}
+
+	return within, dropped, total, true
+}
@@ -0,0 +1,250 @@
+package record
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+// float returns a pointer to f for the optional fields of predicted tests.
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestSelectTests(t *testing.T) {
+	var tests = []struct {
+		name       string
+		tests      []predictedTest
+		budget     time.Duration
+		confidence float64
+		selected   []string
+		dropped    map[string]string
+		duration   float64
+		estimated  bool
+	}{
+		{
+			name: "no-limits",
+			tests: []predictedTest{
+				{Path: "a", Duration: float(2)},
+				{Path: "b"},
+			},
+			selected:  []string{"a", "b"},
+			dropped:   map[string]string{},
+			duration:  4,
+			estimated: true,
+		},
+		{
+			name: "budget",
+			tests: []predictedTest{
+				{Path: "a", Duration: float(30)},
+				{Path: "b", Duration: float(50)},
+				{Path: "c", Duration: float(20)},
+				{Path: "d", Duration: float(15)},
+			},
+			budget:   time.Minute,
+			selected: []string{"a", "c"},
+			dropped: map[string]string{
+				"b": dropOverBudget,
+				"d": dropOverBudget,
+			},
+			duration:  50,
+			estimated: true,
+		},
+		{
+			name: "budget-mean-duration",
+			tests: []predictedTest{
+				{Path: "a", Duration: float(40)},
+				{Path: "b"},
+				{Path: "c", Duration: float(10)},
+			},
+			budget:   time.Minute,
+			selected: []string{"a", "c"},
+			dropped: map[string]string{
+				"b": dropOverBudget,
+			},
+			duration:  50,
+			estimated: true,
+		},
+		{
+			name: "budget-without-durations",
+			tests: []predictedTest{
+				{Path: "a"},
+				{Path: "b"},
+			},
+			budget:    time.Second,
+			selected:  []string{"a", "b"},
+			dropped:   map[string]string{},
+			estimated: false,
+		},
+		{
+			name: "confidence",
+			tests: []predictedTest{
+				{Path: "a", Confidence: float(0.9)},
+				{Path: "b", Confidence: float(0.2)},
+				{Path: "c"},
+			},
+			confidence: 0.5,
+			selected:   []string{"a", "c"},
+			dropped: map[string]string{
+				"b": dropLowConfidence,
+			},
+			estimated: false,
+		},
+		{
+			name: "confidence-and-budget",
+			tests: []predictedTest{
+				{Path: "a", Duration: float(50), Confidence: float(0.3)},
+				{Path: "b", Duration: float(40), Confidence: float(0.8)},
+				{Path: "c", Duration: float(30), Confidence: float(0.6)},
+			},
+			budget:     time.Minute,
+			confidence: 0.5,
+			selected:   []string{"b"},
+			dropped: map[string]string{
+				"a": dropLowConfidence,
+				"c": dropOverBudget,
+			},
+			duration:  40,
+			estimated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			selected, dropped, d, estimated := selectTests(tt.tests, tt.budget, tt.confidence)
+
+			assert.Equal(tt.selected, testPaths(selected))
+
+			reasons := make(map[string]string)
+			for _, d := range dropped {
+				reasons[d.Path] = d.Reason
+			}
+			assert.Equal(tt.dropped, reasons)
+
+			assert.Equal(tt.duration, d)
+			assert.Equal(tt.estimated, estimated)
+		})
+	}
+}
+
+func TestPredictBudget(t *testing.T) {
+	var tests = []struct {
+		name     string
+		options  PredictOptions
+		handler  http.HandlerFunc
+		expected string
+		err      string
+	}{
+		{
+			name: "server-budget",
+			options: PredictOptions{
+				Budget: 10 * time.Second,
+			},
+			expected: "^(TestA|TestC)$",
+		},
+		{
+			name: "client-budget",
+			options: PredictOptions{
+				Budget: 10 * time.Second,
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"testFiles": [
+					{"path": "TestA", "duration": 6},
+					{"path": "TestB", "duration": 8},
+					{"path": "TestC", "duration": 3}
+				]}`))
+			},
+			expected: "^(TestA|TestC)$",
+		},
+		{
+			name: "budget-without-durations",
+			options: PredictOptions{
+				Budget: 10 * time.Second,
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"testFiles": [{"path": "TestA"}, {"path": "TestB"}]}`))
+			},
+			err: "failed to apply budget 10s: predicted tests have no durations",
+		},
+		{
+			name: "confidence",
+			options: PredictOptions{
+				Confidence: 0.5,
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"testFiles": [
+					{"path": "TestA", "confidence": 0.4},
+					{"path": "TestB", "confidence": 0.5},
+					{"path": "TestC"}
+				]}`))
+			},
+			expected: "^(TestB|TestC)$",
+		},
+		{
+			name: "invalid-budget",
+			options: PredictOptions{
+				Budget: -time.Second,
+			},
+			err: "invalid budget -1s",
+		},
+		{
+			name: "invalid-confidence",
+			options: PredictOptions{
+				Confidence: 1.5,
+			},
+			err: "invalid confidence 1.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slogt.New(t)
+			assert := assert.New(t)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			srv.Durations = map[string]float64{
+				"TestA": 6,
+				"TestB": 8,
+				"TestC": 3,
+			}
+			if tt.handler != nil {
+				srv.Handlers.Predict = tt.handler
+			}
+
+			var out bytes.Buffer
+			opt := tt.options
+			opt.Repo = "testdata/feature/repo"
+			opt.Runner = "go-test"
+			opt.Stdin = strings.NewReader("TestA\nTestB\nTestC\n")
+			opt.Stdout = &out
+
+			err := Predict(l, srv.Env, opt)
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tt.expected, out.String())
+
+			if tt.handler == nil && assert.Len(srv.PredictLimits, 1) {
+				assert.Equal(tt.options.Budget.Seconds(), *srv.PredictLimits[0].Budget)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"log/slog"
 	"os"
 	"time"
@@ -44,6 +45,12 @@ func main() {
 	}()
 
 	if err := cmd.Root.Execute(); err != nil {
+		// Preserve the exit code of the test command of `record run`.
+		var exit *cmd.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.Code)
+		}
+
 		l.Error("failed to run", "err", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/testlabtools/record"
+)
+
+// bundleCmd represents the bundle command
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Work with bundle files (.tar.zst)",
+}
+
+// inspectCmd represents the bundle inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <bundle.tar.zst>",
+	Short: "List the files of a bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		setup := setupCommand(cmd, args)
+
+		file := args[0]
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		info, err := record.InspectBundle(f)
+		if err != nil {
+			return err
+		}
+
+		setup.log.Debug("inspected bundle", "file", file, "entries", len(info.Entries))
+
+		var out io.Writer = os.Stdout
+		if val := ctx.Value("stdout"); val != nil {
+			out = val.(io.Writer)
+		}
+
+		if cmd.Flag("json").Value.String() == "true" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(info)
+		}
+
+		return printBundle(out, info)
+	},
+}
+
+func printBundle(w io.Writer, info *record.BundleInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSIZE\tPATH")
+	for _, e := range info.Entries {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", e.Name, e.Size, e.Path)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if info.CodeOwners != "" {
+		fmt.Fprintf(w, "\n==> CODEOWNERS <==\n%s", info.CodeOwners)
+	}
+
+	if info.GitSummary != nil {
+		buf, err := json.MarshalIndent(info.GitSummary, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\n==> %s <==\n%s\n", record.GitSummaryFileName, buf)
+	}
+
+	return nil
+}
+
+func init() {
+	Root.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().Bool("json", false, "print the bundle info as JSON")
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestBundleInspectCommand(t *testing.T) {
+	var tests = []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, stdout string)
+	}{
+		{
+			name: "text",
+			check: func(t *testing.T, stdout string) {
+				assert.Contains(t, stdout, "reports/1.xml  445   ../testdata/github/reports/e2e-1.xml\n")
+				assert.Contains(t, stdout, "==> CODEOWNERS <==\n*.go @org/team1\n")
+				assert.Contains(t, stdout, "==> git.json <==\n{\n  \"diffStat\": {\n")
+			},
+		},
+		{
+			name: "json",
+			args: []string{"--json"},
+			check: func(t *testing.T, stdout string) {
+				var info record.BundleInfo
+				err := json.Unmarshal([]byte(stdout), &info)
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Len(t, info.Entries, 5)
+				assert.Equal(t, "../testdata/github/reports/e2e-2.xml", info.Entries[1].Path)
+				assert.NotNil(t, info.GitSummary)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			l := slogt.New(t)
+			slog.SetDefault(l)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			bundle := filepath.Join(t.TempDir(), "bundle.tar.zst")
+			err := record.Upload(l, srv.Env, record.UploadOptions{
+				Repo:    "../testdata/github/repo",
+				Reports: []string{"../testdata/github/reports"},
+				Output:  bundle,
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			ctx := context.WithValue(context.Background(), "env", srv.Env)
+
+			var stdout bytes.Buffer
+			ctx = context.WithValue(ctx, "stdout", &stdout)
+
+			os.Args = append([]string{"record", "bundle", "inspect", bundle}, tt.args...)
+
+			err = inspectCmd.ExecuteContext(ctx)
+			if !assert.NoError(err) {
+				return
+			}
+
+			tt.check(t, stdout.String())
+		})
+	}
+}
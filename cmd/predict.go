@@ -14,20 +14,43 @@ import (
 var predictCmd = &cobra.Command{
 	Use:   "predict",
 	Short: "Predict CI and test results using TestLab",
+	Long: `Predict reads all tests in the --runner format from stdin and writes the
+predicted test selection to stdout.
+
+If no test is selected, the selection runs no tests, e.g. ^()$ for go-test
+or none() for nextest, so an empty shard does not run the whole suite.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
 		setup := setupCommand(cmd, args)
 
-		// PWD can return any symlink and EvalSymlinks resolves the link to an
-		// absolute path.
-		link := setup.env["PWD"]
-		wd, err := filepath.EvalSymlinks(link)
+		wd, err := workDir(setup)
+		if err != nil {
+			return err
+		}
+
+		shardIndex, err := cmd.Flags().GetInt("shard-index")
+		if err != nil {
+			return err
+		}
+		shardTotal, err := cmd.Flags().GetInt("shard-total")
+		if err != nil {
+			return err
+		}
+
+		budget, err := cmd.Flags().GetDuration("budget")
+		if err != nil {
+			return err
+		}
+		confidence, err := cmd.Flags().GetFloat64("confidence")
 		if err != nil {
-			return fmt.Errorf("failed to eval symlink of workdir %q: %w", link, err)
+			return err
 		}
 
-		setup.log.Debug("predict workdir", "wd", wd, "link", link)
+		explain, err := cmd.Flags().GetBool("explain")
+		if err != nil {
+			return err
+		}
 
 		o := record.PredictOptions{
 			Repo: cmd.Flag("repo").Value.String(),
@@ -36,10 +59,22 @@ var predictCmd = &cobra.Command{
 
 			Runner: cmd.Flag("runner").Value.String(),
 
+			ShardIndex: shardIndex,
+			ShardTotal: shardTotal,
+
+			Budget:     budget,
+			Confidence: confidence,
+
+			Fallback: record.Fallback(cmd.Flag("fallback").Value.String()),
+
+			Explain:     explain,
+			ExplainFile: cmd.Flag("explain-file").Value.String(),
+
 			Debug: setup.debug,
 
 			Stdin:  os.Stdin,
 			Stdout: os.Stdout,
+			Stderr: os.Stderr,
 		}
 
 		if in := ctx.Value("stdin"); in != nil {
@@ -48,11 +83,29 @@ var predictCmd = &cobra.Command{
 		if out := ctx.Value("stdout"); out != nil {
 			o.Stdout = out.(io.Writer)
 		}
+		if out := ctx.Value("stderr"); out != nil {
+			o.Stderr = out.(io.Writer)
+		}
 
 		return record.Predict(setup.log, setup.env, o)
 	},
 }
 
+// workDir returns the resolved working directory of the command.
+func workDir(setup setup) (string, error) {
+	// PWD can return any symlink and EvalSymlinks resolves the link to an
+	// absolute path.
+	link := setup.env["PWD"]
+	wd, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return "", fmt.Errorf("failed to eval symlink of workdir %q: %w", link, err)
+	}
+
+	setup.log.Debug("command workdir", "wd", wd, "link", link)
+
+	return wd, nil
+}
+
 func init() {
 	Root.AddCommand(predictCmd)
 
@@ -66,4 +119,11 @@ func init() {
 	// is called directly, e.g.:
 	// predictCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	predictCmd.Flags().String("runner", "", "name of the test runner format")
+	predictCmd.Flags().Int("shard-index", 0, "1-based index of the shard of predicted tests")
+	predictCmd.Flags().Int("shard-total", 0, "total number of shards of predicted tests")
+	predictCmd.Flags().Duration("budget", 0, "maximum estimated runtime of the predicted tests, e.g. 10m (fails if the API returns no test durations)")
+	predictCmd.Flags().Float64("confidence", 0, "minimum confidence (0-1) of a predicted test")
+	predictCmd.Flags().Bool("explain", false, "write a table that explains the test selection to stderr")
+	predictCmd.Flags().String("explain-file", "", "write a JSON report that explains the test selection to the file")
+	predictCmd.Flags().String("fallback", string(record.FallbackAll), "test selection if the prediction fails (all, local or none)")
 }
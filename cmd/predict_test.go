@@ -12,6 +12,7 @@ import (
 
 	"github.com/neilotoole/slogt"
 	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record"
 	"github.com/testlabtools/record/client"
 	"github.com/testlabtools/record/fake"
 	"github.com/testlabtools/record/runner"
@@ -23,6 +24,7 @@ func TestPredictCommand(t *testing.T) {
 		args   []string
 		stdin  string
 		stdout interface{}
+		setup  func(srv *fake.FakeServer)
 		check  func(t *testing.T, srv *fake.FakeServer)
 	}{
 		{
@@ -36,6 +38,127 @@ func TestPredictCommand(t *testing.T) {
 				assert.Empty(t, srv.Predicts)
 			},
 		},
+		{
+			name: "empty-jest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "jest",
+			},
+			stdout: "{\"testMatch\":[]}\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-pytest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "pytest",
+			},
+			stdout: "-k 'x and not x'\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-playwright",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "playwright",
+			},
+			stdout: "--grep '^$'\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-cypress",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "cypress",
+			},
+			stdout: "/dev/null/*\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-gradle",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "gradle",
+			},
+			stdout: "-x test\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-maven",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "maven",
+			},
+			stdout: "-DskipTests\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-rspec",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "rspec",
+			},
+			stdout: "--tag testlab_none\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-minitest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "minitest",
+			},
+			stdout: "-n '/^$/'\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-nextest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "nextest",
+			},
+			stdout: "none()\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-vitest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "vitest",
+			},
+			stdout: "--testNamePattern '^$'\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
+		{
+			name: "empty-mocha",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "mocha",
+			},
+			stdout: "--grep '^$'\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Empty(t, srv.Predicts)
+			},
+		},
 		{
 			name: "github-two-go-test",
 			args: []string{
@@ -52,6 +175,76 @@ TestUploadCommand
 				assert.Len(t, srv.Predicts[0].TestFiles, 2)
 			},
 		},
+		{
+			name: "github-budget-go-test",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "go-test",
+				"--budget", "1m",
+			},
+			stdin: `TestPredictCommand
+TestUploadCommand
+TestRootCommand
+`,
+			setup: func(srv *fake.FakeServer) {
+				srv.Durations = map[string]float64{
+					"TestPredictCommand": 40,
+					"TestUploadCommand":  30,
+					"TestRootCommand":    20,
+				}
+			},
+			stdout: "^(TestPredictCommand|TestRootCommand)$",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				if assert.Len(t, srv.PredictLimits, 1) {
+					assert.Equal(t, 60.0, *srv.PredictLimits[0].Budget)
+					assert.Nil(t, srv.PredictLimits[0].Confidence)
+				}
+			},
+		},
+		{
+			name: "github-shard-go-test",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "go-test",
+				"--shard-index", "2",
+				"--shard-total", "2",
+			},
+			stdin: `TestPredictCommand
+TestUploadCommand
+TestRootCommand
+`,
+			stdout: "^(TestRootCommand)$",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Len(t, srv.Predicts, 1)
+				assert.Len(t, srv.Predicts[0].TestFiles, 3)
+			},
+		},
+		{
+			name: "github-json-go-test",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "go-test",
+			},
+			stdin: `{"Action":"output","Package":"github.com/testlabtools/record","Output":"TestUpload\n"}
+{"Action":"output","Package":"github.com/testlabtools/record/cmd","Output":"TestUpload\n"}
+`,
+			stdout: "-run '^(TestUpload)$' github.com/testlabtools/record\n" +
+				"-run '^(TestUpload)$' github.com/testlabtools/record/cmd\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				if !assert.Len(t, srv.Predicts, 1) {
+					return
+				}
+				var paths []string
+				for _, f := range srv.Predicts[0].TestFiles {
+					paths = append(paths, f.Path)
+				}
+				expected := []string{
+					"github.com/testlabtools/record/TestUpload",
+					"github.com/testlabtools/record/cmd/TestUpload",
+				}
+				assert.Equal(t, expected, paths)
+			},
+		},
 		{
 			name: "github-two-jest",
 			args: []string{
@@ -76,7 +269,327 @@ $pwd$/app/web/quux.test.ts
 				assert.Len(t, srv.Predicts[0].TestFiles, 2)
 			},
 		},
+		{
+			name: "github-two-pytest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "pytest",
+			},
+			stdin: `app/api/tests/test_orders.py::TestOrders::test_create
+app/api/tests/test_users.py::test_list
+app/api/tests/test_users.py::test_list[a b]
+
+3 tests collected in 0.01s
+`,
+			stdout: "app/api/tests/test_orders.py app/api/tests/test_users.py\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				if !assert.Len(t, srv.Predicts, 1) {
+					return
+				}
+				expected := []client.PredictTestFile{
+					{Path: "/app/api/tests/test_orders.py"},
+					{Path: "/app/api/tests/test_users.py"},
+				}
+				assert.Equal(t, expected, srv.Predicts[0].TestFiles)
+			},
+		},
+		{
+			name: "github-pytest-counts",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "pytest",
+			},
+			stdin: `$pwd$/app/api/tests/test_users.py: 2
+
+2 tests collected in 0.01s
+`,
+			stdout: "app/api/tests/test_users.py\n",
+		},
+		{
+			name: "github-two-playwright",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "playwright",
+			},
+			stdin: `{
+  "config": {
+    "rootDir": "$pwd$/app/e2e"
+  },
+  "suites": [
+    {
+      "title": "auth/login.spec.ts",
+      "file": "auth/login.spec.ts",
+      "specs": [{"title": "logs in", "file": "auth/login.spec.ts"}],
+      "suites": [
+        {"title": "admin", "file": "auth/login.spec.ts", "specs": []}
+      ]
+    },
+    {
+      "title": "checkout.spec.ts",
+      "file": "checkout.spec.ts",
+      "specs": [{"title": "checks out", "file": "checkout.spec.ts"}]
+    }
+  ],
+  "errors": []
+}
+`,
+			stdout: "app/e2e/auth/login.spec.ts app/e2e/checkout.spec.ts\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				if !assert.Len(t, srv.Predicts, 1) {
+					return
+				}
+				expected := []client.PredictTestFile{
+					{Path: "/app/e2e/auth/login.spec.ts"},
+					{Path: "/app/e2e/checkout.spec.ts"},
+				}
+				assert.Equal(t, expected, srv.Predicts[0].TestFiles)
+			},
+		},
+		{
+			name: "github-two-cypress",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "cypress",
+			},
+			stdin: `app/cypress/e2e/login.cy.ts
+$pwd$/app/cypress/e2e/signup.cy.ts
+`,
+			stdout: "app/cypress/e2e/login.cy.ts,app/cypress/e2e/signup.cy.ts\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Len(t, srv.Predicts, 1)
+				assert.Len(t, srv.Predicts[0].TestFiles, 2)
+			},
+		},
+		{
+			name: "github-two-gradle",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "gradle",
+			},
+			stdin: `> Task :service:test
+com.example.UserServiceTest > listsUsers PASSED
+com.example.UserServiceTest$Nested > nested PASSED
+com.example.OrderServiceTest > createsOrder PASSED
+com.example.GeneratedTest > generated PASSED
+`,
+			stdout: "--tests com.example.UserServiceTest --tests com.example.OrderServiceTest --tests com.example.GeneratedTest\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				if !assert.Len(t, srv.Predicts, 1) {
+					return
+				}
+				expected := []client.PredictTestFile{
+					{Path: "/app/service/src/test/java/com/example/UserServiceTest.java"},
+					{Path: "/app/service/src/test/kotlin/com/example/OrderServiceTest.kt"},
+					{Path: "com.example.GeneratedTest"},
+				}
+				assert.Equal(t, expected, srv.Predicts[0].TestFiles)
+			},
+		},
+		{
+			name: "github-two-maven",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "maven",
+			},
+			stdin: `app/service/target/test-classes/com/example/UserServiceTest.class
+app/service/target/test-classes/com/example/UserServiceTest$Nested.class
+app/service/src/test/kotlin/com/example/OrderServiceTest.kt
+`,
+			stdout: "-Dtest=com.example.UserServiceTest,com.example.OrderServiceTest\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Len(t, srv.Predicts, 1)
+				assert.Len(t, srv.Predicts[0].TestFiles, 2)
+			},
+		},
+		{
+			name: "github-two-rspec",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "rspec",
+			},
+			stdin: `DEPRECATION WARNING: some deprecation
+{"version":"3.12.3","examples":[{"id":"./app/rails/spec/models/user_spec.rb[1:1]","description":"is valid","full_description":"User is valid","status":"passed","file_path":"./app/rails/spec/models/user_spec.rb","line_number":2,"run_time":0.0,"pending_message":null},{"id":"./app/rails/spec/models/user_spec.rb[1:2]","description":"is admin","full_description":"User is admin","status":"passed","file_path":"./app/rails/spec/models/user_spec.rb","line_number":4,"run_time":0.0,"pending_message":null},{"id":"./app/rails/spec/models/order_spec.rb[1:1]","description":"totals","full_description":"Order totals","status":"passed","file_path":"./app/rails/spec/models/order_spec.rb","line_number":2,"run_time":0.0,"pending_message":null}],"summary":{"duration":0.001,"example_count":3,"failure_count":0,"pending_count":0,"errors_outside_of_examples_count":0},"summary_line":"3 examples, 0 failures"}
+`,
+			stdout: "app/rails/spec/models/user_spec.rb app/rails/spec/models/order_spec.rb\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				if !assert.Len(t, srv.Predicts, 1) {
+					return
+				}
+				expected := []client.PredictTestFile{
+					{Path: "/app/rails/spec/models/user_spec.rb"},
+					{Path: "/app/rails/spec/models/order_spec.rb"},
+				}
+				assert.Equal(t, expected, srv.Predicts[0].TestFiles)
+			},
+		},
+		{
+			name: "github-two-minitest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "minitest",
+			},
+			stdin: `app/rails/test/controllers/orders_controller_test.rb
+$pwd$/app/rails/test/controllers/users_controller_test.rb
+`,
+			stdout: "app/rails/test/controllers/orders_controller_test.rb app/rails/test/controllers/users_controller_test.rb\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Len(t, srv.Predicts, 1)
+				assert.Len(t, srv.Predicts[0].TestFiles, 2)
+			},
+		},
+		{
+			name: "github-nextest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "nextest",
+			},
+			stdin: `{"rust-build-meta":{"target-directory":"/tmp/target","base-output-directories":["debug"]},"test-count":5,"rust-suites":{
+"rust::cli":{"package-name":"rust","binary-id":"rust::cli","binary-name":"cli","kind":"test","cwd":"$pwd$/app/rust","status":"listed","testcases":{"end_to_end":{"ignored":false,"filter-match":{"status":"matches"}}}},
+"rust":{"package-name":"rust","binary-id":"rust","binary-name":"rust","kind":"lib","cwd":"$pwd$/app/rust","status":"listed","testcases":{"tests::it_works":{"ignored":false,"filter-match":{"status":"matches"}},"parser::tests::parses":{"ignored":false,"filter-match":{"status":"matches"}},"parser::tests::fails":{"ignored":false,"filter-match":{"status":"matches"}}}},
+"rust::bin/tool":{"package-name":"rust","binary-id":"rust::bin/tool","binary-name":"tool","kind":"bin","cwd":"$pwd$/app/rust","status":"listed","testcases":{"tests::runs":{"ignored":false,"filter-match":{"status":"matches"}}}}
+}}
+`,
+			stdout: "binary_id(=rust) | binary_id(=rust::bin/tool) | binary_id(=rust::cli)\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				if !assert.Len(t, srv.Predicts, 1) {
+					return
+				}
+				expected := []client.PredictTestFile{
+					{Path: "/app/rust/src/parser/mod.rs"},
+					{Path: "/app/rust/src/lib.rs"},
+					{Path: "rust::bin/tool::tests::runs"},
+					{Path: "/app/rust/tests/cli.rs"},
+				}
+				assert.Equal(t, expected, srv.Predicts[0].TestFiles)
+			},
+		},
+		{
+			name: "github-two-vitest",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "vitest",
+			},
+			stdin: `[
+  {"name": "baz > renders", "file": "$pwd$/app/web/baz.test.ts"},
+  {"name": "baz > updates", "file": "$pwd$/app/web/baz.test.ts"},
+  {"name": "quux > renders", "file": "$pwd$/app/web/quux.test.ts", "projectName": "web"}
+]
+`,
+			stdout: "app/web/baz.test.ts app/web/quux.test.ts\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				if !assert.Len(t, srv.Predicts, 1) {
+					return
+				}
+				expected := []client.PredictTestFile{
+					{Path: "/app/web/baz.test.ts"},
+					{Path: "/app/web/quux.test.ts"},
+				}
+				assert.Equal(t, expected, srv.Predicts[0].TestFiles)
+			},
+		},
+		{
+			name: "github-two-mocha",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "mocha",
+			},
+			stdin: `app/api/test/orders.spec.js
+app/api/test/users.spec.js
+`,
+			stdout: "app/api/test/orders.spec.js app/api/test/users.spec.js\n",
+			check: func(t *testing.T, srv *fake.FakeServer) {
+				assert.Len(t, srv.Predicts, 1)
+				assert.Len(t, srv.Predicts[0].TestFiles, 2)
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, ci := range providers {
+			t.Run(tt.name+"/"+string(ci), func(t *testing.T) {
+				assert := assert.New(t)
+
+				l := slogt.New(t)
+				slog.SetDefault(l)
+
+				srv := fake.NewServer(t, l, ci)
+				defer srv.Close()
+
+				cwd, _ := os.Getwd()
+				cwd = path.Join(cwd, "testdata", "symlink")
+				srv.Env["PWD"] = cwd
+
+				if tt.setup != nil {
+					tt.setup(srv)
+				}
+
+				ctx := context.WithValue(context.Background(), "env", srv.Env)
+
+				stdin := strings.ReplaceAll(tt.stdin, "$pwd$", cwd)
+				ctx = context.WithValue(ctx, "stdin", strings.NewReader(stdin))
+
+				var stdout bytes.Buffer
+				ctx = context.WithValue(ctx, "stdout", &stdout)
+
+				t.Cleanup(func() {
+					predictCmd.Flags().Set("shard-index", "0")
+					predictCmd.Flags().Set("shard-total", "0")
+					predictCmd.Flags().Set("budget", "0s")
+					predictCmd.Flags().Set("confidence", "0")
+					predictCmd.Flags().Set("explain", "false")
+					predictCmd.Flags().Set("explain-file", "")
+					predictCmd.Flags().Set("fallback", string(record.FallbackAll))
+				})
+
+				os.Args = append([]string{"record", "predict"}, tt.args...)
+
+				err := predictCmd.ExecuteContext(ctx)
+				if !assert.NoError(err) {
+					return
+				}
+
+				if tt.check != nil {
+					tt.check(t, srv)
+				}
+
+				if _, ok := tt.stdout.(string); ok {
+					assert.Equal(tt.stdout, stdout.String())
+				} else {
+					var buf bytes.Buffer
+					json.NewEncoder(&buf).Encode(tt.stdout)
+					assert.JSONEq(buf.String(), stdout.String())
+				}
+			})
+		}
+	}
+}
+
+func TestPredictExplain(t *testing.T) {
+	var tests = []struct {
+		name   string
+		args   func(file string) []string
+		stderr bool
+	}{
+		{
+			name: "file",
+			args: func(file string) []string {
+				return []string{"--explain-file=" + file}
+			},
+		},
+		{
+			name: "file-without-equals",
+			args: func(file string) []string {
+				return []string{"--explain-file", file}
+			},
+		},
+		{
+			name: "file-and-table",
+			args: func(file string) []string {
+				return []string{"--explain", "--explain-file", file}
+			},
+			stderr: true,
+		},
 	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			assert := assert.New(t)
@@ -87,35 +600,50 @@ $pwd$/app/web/quux.test.ts
 			srv := fake.NewServer(t, l, client.Github)
 			defer srv.Close()
 
-			cwd, _ := os.Getwd()
-			cwd = path.Join(cwd, "testdata", "symlink")
-			srv.Env["PWD"] = cwd
-
 			ctx := context.WithValue(context.Background(), "env", srv.Env)
+			ctx = context.WithValue(ctx, "stdin", strings.NewReader("TestPredictCommand\nTestUploadCommand\n"))
 
-			stdin := strings.ReplaceAll(tt.stdin, "$pwd$", cwd)
-			ctx = context.WithValue(ctx, "stdin", strings.NewReader(stdin))
-
-			var stdout bytes.Buffer
+			var stdout, stderr bytes.Buffer
 			ctx = context.WithValue(ctx, "stdout", &stdout)
+			ctx = context.WithValue(ctx, "stderr", &stderr)
+
+			t.Cleanup(func() {
+				predictCmd.Flags().Set("explain", "false")
+				predictCmd.Flags().Set("explain-file", "")
+			})
 
-			os.Args = append([]string{"record", "predict"}, tt.args...)
+			file := path.Join(t.TempDir(), "explain.json")
+
+			args := []string{"--repo", "../testdata/github/repo", "--runner", "go-test"}
+			os.Args = append([]string{"record", "predict"}, append(args, tt.args(file)...)...)
 
 			err := predictCmd.ExecuteContext(ctx)
 			if !assert.NoError(err) {
 				return
 			}
 
-			if tt.check != nil {
-				tt.check(t, srv)
+			assert.Equal("^(TestPredictCommand|TestUploadCommand)$", stdout.String())
+			if tt.stderr {
+				assert.Contains(stderr.String(), "strategy: api\n")
+			} else {
+				assert.Empty(stderr.String())
 			}
 
-			if _, ok := tt.stdout.(string); ok {
-				assert.Equal(tt.stdout, stdout.String())
-			} else {
-				var buf bytes.Buffer
-				json.NewEncoder(&buf).Encode(tt.stdout)
-				assert.JSONEq(buf.String(), stdout.String())
+			buf, err := os.ReadFile(file)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var report struct {
+				Strategy string `json:"strategy"`
+				Tests    []struct {
+					Path     string `json:"path"`
+					Selected bool   `json:"selected"`
+				} `json:"tests"`
+			}
+			if assert.NoError(json.Unmarshal(buf, &report)) {
+				assert.Equal("api", report.Strategy)
+				assert.Len(report.Tests, 2)
 			}
 		})
 	}
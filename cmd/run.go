@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/testlabtools/record"
+)
+
+// ExitError is returned when the test command exits with a non-zero code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("test command exited with code %d", e.Code)
+}
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- <test command>",
+	Short: "Predict, run and upload tests using TestLab",
+	Long: `Run lists all tests with the --list command, predicts which tests to
+run, executes the test command and uploads its reports to TestLab.
+
+Each {} in the test command is replaced by the predicted test selection in
+the --runner format. If the selection has several lines, like the go-test
+format of 'go test -list . -json' with one line per package, the test command
+runs once per line. The exit code of the test command is preserved, or the
+first non-zero one of several runs. If no test is selected, the test command
+does not run and no reports are uploaded.`,
+	Example: `  record run --runner go-test --list 'go test -list . ./...' \
+    --reports reports/ -- sh -c 'go test -run "{}" ./... | go-junit-report > reports/junit.xml'
+
+  record run --runner go-test --list 'go test -list . -json ./...' \
+    --reports reports/ -- sh -c 'go test -v {} | go-junit-report > reports/junit-$$.xml'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		setup := setupCommand(cmd, args)
+
+		wd, err := workDir(setup)
+		if err != nil {
+			return err
+		}
+
+		reports, err := cmd.Flags().GetStringArray("reports")
+		if err != nil {
+			return err
+		}
+		include, err := cmd.Flags().GetStringArray("include")
+		if err != nil {
+			return err
+		}
+		exclude, err := cmd.Flags().GetStringArray("exclude")
+		if err != nil {
+			return err
+		}
+
+		o := record.RunOptions{
+			Repo: cmd.Flag("repo").Value.String(),
+
+			WorkDir: wd,
+
+			Runner: cmd.Flag("runner").Value.String(),
+			List:   cmd.Flag("list").Value.String(),
+
+			Command: args,
+
+			Reports: splitLines(reports),
+			Include: splitLines(include),
+			Exclude: splitLines(exclude),
+
+			InvalidReports: record.InvalidReports(cmd.Flag("invalid-reports").Value.String()),
+
+			Debug: setup.debug,
+
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+		}
+
+		if out := ctx.Value("stdout"); out != nil {
+			o.Stdout = out.(io.Writer)
+		}
+
+		code, err := record.Run(setup.log, setup.env, o)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return &ExitError{Code: code}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	Root.AddCommand(runCmd)
+
+	runCmd.Flags().String("runner", "", "name of the test runner format")
+	runCmd.Flags().String("list", "", "shell command listing all tests in the runner format")
+
+	runCmd.Flags().StringArray("reports", []string{"junit-reports"}, "path to JUnit report files, directories or glob patterns (repeatable, supports **)")
+	runCmd.Flags().StringArray("include", nil, "glob pattern of report files to include, e.g. '*.xml' or 'e2e/**/*.xml' relative to the reports path (repeatable)")
+	runCmd.Flags().StringArray("exclude", nil, "glob pattern of report files to exclude, e.g. '*.log' or 'tmp/**' relative to the reports path (repeatable)")
+	runCmd.Flags().String("invalid-reports", string(record.InvalidReportsOmit), "policy for invalid JUnit reports (*.xml files): omit, fail or keep")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestRunCommand(t *testing.T) {
+	var tests = []struct {
+		name   string
+		args   []string
+		stdout string
+		code   int
+	}{
+		{
+			name: "go-test",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--runner", "go-test",
+				"--list", `printf 'TestRunCommand\n'`,
+				"--reports", "../testdata/github/reports",
+				"--", "echo", "-run={}",
+			},
+			stdout: "-run=^(TestRunCommand)$\n",
+		},
+		{
+			name: "exit-code",
+			args: []string{
+				"--repo", "../testdata/github/repo",
+				"--reports", "../testdata/github/reports",
+				"--", "sh", "-c", "exit 5",
+			},
+			code: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			l := slogt.New(t)
+			slog.SetDefault(l)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			cwd, _ := os.Getwd()
+			srv.Env["PWD"] = cwd
+
+			ctx := context.WithValue(context.Background(), "env", srv.Env)
+
+			var stdout bytes.Buffer
+			ctx = context.WithValue(ctx, "stdout", &stdout)
+
+			os.Args = append([]string{"record", "run"}, tt.args...)
+
+			err := runCmd.ExecuteContext(ctx)
+			if tt.code != 0 {
+				var exit *ExitError
+				if assert.ErrorAs(err, &exit) {
+					assert.Equal(tt.code, exit.Code)
+				}
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tt.stdout, stdout.String())
+			assert.Len(srv.Files, 1)
+		})
+	}
+}
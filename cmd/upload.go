@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -9,8 +13,10 @@ import (
 
 // uploadCmd represents the upload command
 var uploadCmd = &cobra.Command{
-	Use:   "upload",
+	Use:   "upload [flags] [-- <test command>]",
 	Short: "Upload CI and test run results to TestLab",
+	Example: `  record upload --reports reports/
+  record upload --watch --reports reports/ -- npx playwright test`,
 	// Long: `A longer description that spans multiple lines and likely contains examples
 	// and usage of using your command. For example:
 	//
@@ -20,10 +26,39 @@ var uploadCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		setup := setupCommand(cmd, args)
 
+		reports, err := cmd.Flags().GetStringArray("reports")
+		if err != nil {
+			return err
+		}
+		include, err := cmd.Flags().GetStringArray("include")
+		if err != nil {
+			return err
+		}
+		exclude, err := cmd.Flags().GetStringArray("exclude")
+		if err != nil {
+			return err
+		}
+
+		partSize, err := cmd.Flags().GetInt64("part-size")
+		if err != nil {
+			return err
+		}
+		if partSize != 0 && partSize<<20 < record.MinPartSize {
+			return fmt.Errorf("part size must be at least %d MiB", record.MinPartSize>>20)
+		}
+
 		o := record.UploadOptions{
 			Repo:    cmd.Flag("repo").Value.String(),
-			Reports: cmd.Flag("reports").Value.String(),
+			Reports: splitLines(reports),
+			Include: splitLines(include),
+			Exclude: splitLines(exclude),
+			Output:  cmd.Flag("output").Value.String(),
+			From:    cmd.Flag("from").Value.String(),
 			Debug:   setup.debug,
+
+			PartSize: partSize << 20,
+
+			InvalidReports: record.InvalidReports(cmd.Flag("invalid-reports").Value.String()),
 		}
 
 		started := cmd.Flag("started").Value.String()
@@ -35,10 +70,77 @@ var uploadCmd = &cobra.Command{
 			o.Started = &val
 		}
 
-		return record.Upload(setup.log, setup.env, o)
+		watch, err := cmd.Flags().GetBool("watch")
+		if err != nil {
+			return err
+		}
+
+		if !watch {
+			if len(args) > 0 {
+				return fmt.Errorf("test command requires --watch")
+			}
+			return record.Upload(setup.log, setup.env, o)
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("--watch requires a test command")
+		}
+
+		interval, err := cmd.Flags().GetDuration("watch-interval")
+		if err != nil {
+			return err
+		}
+
+		wd, err := workDir(setup)
+		if err != nil {
+			return err
+		}
+
+		w := record.WatchOptions{
+			UploadOptions: o,
+
+			Command:  args,
+			WorkDir:  wd,
+			Interval: interval,
+
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+		}
+
+		if out := cmd.Context().Value("stdout"); out != nil {
+			w.Stdout = out.(io.Writer)
+		}
+		if out := cmd.Context().Value("stderr"); out != nil {
+			w.Stderr = out.(io.Writer)
+		}
+
+		code, err := record.Watch(setup.log, setup.env, w)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return &ExitError{Code: code}
+		}
+
+		return nil
 	},
 }
 
+// splitLines splits each value by newlines, since the GitHub action passes
+// all paths as a single newline-separated argument.
+func splitLines(values []string) []string {
+	var out []string
+	for _, val := range values {
+		for _, line := range strings.Split(val, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" {
+				out = append(out, line)
+			}
+		}
+	}
+	return out
+}
+
 func parseStarted(s string) (t time.Time, err error) {
 	formats := []string{
 		time.RFC3339,
@@ -67,5 +169,22 @@ func init() {
 	// is called directly, e.g.:
 	uploadCmd.Flags().String("started", "", "set run's start time (ISO 8601 format)")
 
-	uploadCmd.Flags().String("reports", "junit-reports", "path to the JUnit reports directory")
+	uploadCmd.Flags().StringArray("reports", []string{"junit-reports"}, "path to JUnit report files, directories or glob patterns (repeatable, supports **)")
+	uploadCmd.Flags().StringArray("include", nil, "glob pattern of report files to include, e.g. '*.xml' or 'e2e/**/*.xml' relative to the reports path (repeatable)")
+	uploadCmd.Flags().StringArray("exclude", nil, "glob pattern of report files to exclude, e.g. '*.log' or 'tmp/**' relative to the reports path (repeatable)")
+
+	uploadCmd.Flags().String("output", "", "save the bundle to a file instead of uploading it")
+	uploadCmd.Flags().String("from", "", "upload a bundle file saved with --output")
+	uploadCmd.MarkFlagsMutuallyExclusive("output", "from")
+
+	uploadCmd.Flags().Bool("watch", false, "run the test command and upload new reports while it runs (reports must not change once written)")
+	uploadCmd.Flags().Duration("watch-interval", record.DefaultWatchInterval, "poll interval of the reports in watch mode")
+	uploadCmd.MarkFlagsMutuallyExclusive("watch", "output")
+	uploadCmd.MarkFlagsMutuallyExclusive("watch", "from")
+
+	uploadCmd.Flags().Int64("part-size", 0, "part size in MiB of multipart uploads for large bundles, e.g. 16 (disabled by default). Resuming needs --from")
+	// Hidden until the API serves multipart uploads, see record.UploadOptions.PartSize.
+	uploadCmd.Flags().MarkHidden("part-size")
+
+	uploadCmd.Flags().String("invalid-reports", string(record.InvalidReportsOmit), "policy for invalid JUnit reports (*.xml files): omit, fail or keep")
 }
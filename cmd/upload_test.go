@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"log/slog"
 	"maps"
@@ -17,6 +18,12 @@ import (
 	"github.com/testlabtools/record/fake"
 )
 
+// providers are the CI providers the command tests run against.
+var providers = []client.CIProviderName{
+	client.Github,
+	client.Gitlab,
+}
+
 func TestParseStarted(t *testing.T) {
 	var tests = []struct {
 		name string
@@ -52,6 +59,20 @@ func TestParseStarted(t *testing.T) {
 
 }
 
+func TestSplitLines(t *testing.T) {
+	values := []string{
+		"reports/\n**/target/surefire-reports/*.xml\n",
+		"  e2e/junit.xml  ",
+		"",
+	}
+	expected := []string{
+		"reports/",
+		"**/target/surefire-reports/*.xml",
+		"e2e/junit.xml",
+	}
+	assert.Equal(t, expected, splitLines(values))
+}
+
 func TestUploadCommand(t *testing.T) {
 	var tests = []struct {
 		name  string
@@ -82,6 +103,7 @@ func TestUploadCommand(t *testing.T) {
 				expected := []string{
 					"CODEOWNERS",
 					record.GitSummaryFileName,
+					record.BundleManifestFileName,
 					"reports/1.xml",
 					"reports/2.xml",
 				}
@@ -106,7 +128,7 @@ func TestUploadCommand(t *testing.T) {
 				"--reports", "../testdata/basic/reports",
 			},
 			check: func(t *testing.T, srv *fake.FakeServer) {
-				key := srv.Env["GITHUB_RUN_ID"] + "-" + srv.Env["TESTLAB_GROUP"]
+				key := srv.RunKey()
 				if !assert.Contains(t, srv.Runs, key) {
 					return
 				}
@@ -120,27 +142,73 @@ func TestUploadCommand(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			assert := assert.New(t)
+		for _, ci := range providers {
+			t.Run(tt.name+"/"+string(ci), func(t *testing.T) {
+				assert := assert.New(t)
 
-			l := slogt.New(t)
-			slog.SetDefault(l)
+				l := slogt.New(t)
+				slog.SetDefault(l)
 
-			srv := fake.NewServer(t, l, client.Github)
-			defer srv.Close()
+				srv := fake.NewServer(t, l, ci)
+				defer srv.Close()
 
-			ctx := context.WithValue(context.Background(), "env", srv.Env)
+				ctx := context.WithValue(context.Background(), "env", srv.Env)
 
-			os.Args = append([]string{"record", "upload"}, tt.args...)
+				os.Args = append([]string{"record", "upload"}, tt.args...)
 
-			err := uploadCmd.ExecuteContext(ctx)
-			if !assert.NoError(err) {
-				return
-			}
+				err := uploadCmd.ExecuteContext(ctx)
+				if !assert.NoError(err) {
+					return
+				}
 
-			if tt.check != nil {
-				tt.check(t, srv)
-			}
-		})
+				if tt.check != nil {
+					tt.check(t, srv)
+				}
+			})
+		}
+	}
+}
+
+func TestUploadWatchCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+	slog.SetDefault(l)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	// Reset the flags, since they persist between command executions.
+	t.Cleanup(func() {
+		uploadCmd.Flags().Set("watch", "false")
+		uploadCmd.Flags().Set("watch-interval", record.DefaultWatchInterval.String())
+	})
+
+	cwd, _ := os.Getwd()
+	srv.Env["PWD"] = cwd
+
+	ctx := context.WithValue(context.Background(), "env", srv.Env)
+
+	var stdout, stderr bytes.Buffer
+	ctx = context.WithValue(ctx, "stdout", &stdout)
+	ctx = context.WithValue(ctx, "stderr", &stderr)
+
+	os.Args = []string{
+		"record", "upload",
+		"--watch",
+		"--watch-interval", "1h",
+		"--reports", "../testdata/github/reports",
+		"--repo", "../testdata/github/repo",
+		"--", "sh", "-c", "echo out; echo err >&2; exit 4",
 	}
+
+	err := uploadCmd.ExecuteContext(ctx)
+	var exit *ExitError
+	if assert.ErrorAs(err, &exit) {
+		assert.Equal(4, exit.Code)
+	}
+
+	assert.Equal("out\n", stdout.String())
+	assert.Equal("err\n", stderr.String())
+	assert.Len(srv.Files, 1)
 }
@@ -6,10 +6,10 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path"
-	"path/filepath"
-	"strconv"
+	"slices"
 	"strings"
 
 	"github.com/testlabtools/record/client"
@@ -42,18 +42,6 @@ func NewCollector(l *slog.Logger, repo string, osEnv map[string]string) (*Collec
 	return c, err
 }
 
-func parseInts(env map[string]string, numeric map[string]*int) error {
-	for key, ref := range numeric {
-		val, err := strconv.Atoi(env[key])
-		if err != nil {
-			return fmt.Errorf("failed to parse %q: %s", key, err)
-		}
-		*ref = val
-	}
-
-	return nil
-}
-
 type RunEnv struct {
 	ActorName      string
 	CIProviderName client.CIProviderName
@@ -123,45 +111,26 @@ func (c *Collector) initEnv() (RunEnv, error) {
 		return RunEnv{}, fmt.Errorf("failed to collect git env: %w", err)
 	}
 
-	if c.osEnv["GITHUB_ACTIONS"] != "" {
-		extra := []string{
-			"GITHUB_BASE_REF",
-			"GITHUB_HEAD_REF",
-			"GITHUB_JOB",
-			"GITHUB_REF_TYPE",
-		}
-
-		for _, key := range extra {
-			val := c.osEnv[key]
-			if val == "" {
-				continue
-			}
-			ciEnv[key] = val
-		}
+	p := detectCIProvider(c.osEnv)
+	if p == nil {
+		return RunEnv{}, fmt.Errorf("unknown CI provider")
+	}
 
-		re := RunEnv{
-			ActorName:      c.osEnv["GITHUB_ACTOR"],
-			CIProviderName: client.Github,
-			GitRef:         c.osEnv["GITHUB_REF"],
-			GitRefName:     c.osEnv["GITHUB_REF_NAME"],
-			GitRepo:        c.osEnv["GITHUB_REPOSITORY"],
-			GitSha:         c.osEnv["GITHUB_SHA"],
-			Group:          group,
-			CIEnv:          &ciEnv,
-		}
+	c.log.Debug("detected CI provider", "name", p.Name())
 
-		numeric := map[string]*int{
-			"GITHUB_RUN_ATTEMPT": &re.RunAttempt,
-			"GITHUB_RUN_ID":      &re.RunId,
-			"GITHUB_RUN_NUMBER":  &re.RunNumber,
+	for _, key := range p.ExtraEnv() {
+		val := c.osEnv[key]
+		if val == "" {
+			continue
 		}
-
-		err := parseInts(c.osEnv, numeric)
-
-		return re, err
+		ciEnv[key] = val
 	}
 
-	return RunEnv{}, fmt.Errorf("unknown CI provider")
+	re, err := p.RunEnv(c.osEnv)
+	re.Group = group
+	re.CIEnv = &ciEnv
+
+	return re, err
 }
 
 func (c *Collector) Env() RunEnv {
@@ -190,51 +159,6 @@ func fileExists(path string) bool {
 	return info.Mode().IsRegular()
 }
 
-func readReports(dir string, limit int) (map[string][]byte, error) {
-	if dir == "" || !dirExists(dir) {
-		return nil, nil
-	}
-
-	files := make(map[string][]byte)
-	i := 0
-
-	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return fmt.Errorf("failed to access path %q: %w", path, err)
-		}
-
-		if d.IsDir() {
-			// Skip directories
-			return nil
-		}
-
-		file, err := os.Open(path)
-		if err != nil {
-			return fmt.Errorf("failed to open file %q: %w", path, err)
-		}
-		defer file.Close()
-
-		content, err := io.ReadAll(file)
-		if err != nil {
-			return fmt.Errorf("failed to read file %q: %w", path, err)
-		}
-
-		// Store the file content using short file names
-		i++
-		name := fmt.Sprintf("reports/%d%s", i, filepath.Ext(path))
-		files[name] = content
-
-		if len(files) > limit {
-			// Avoid bundling a whole repo.
-			return fmt.Errorf("too many files (%d > %d) found", len(files), limit)
-		}
-
-		return nil
-	})
-
-	return files, err
-}
-
 func (c *Collector) findCodeOwners(dir string) string {
 	// https://docs.github.com/en/repositories/managing-your-repositorys-settings-and-features/customizing-your-repository/about-code-owners#codeowners-file-location
 	names := []string{
@@ -337,58 +261,123 @@ func (c *Collector) addGitSummary(files *map[string][]byte) error {
 type BundleOptions struct {
 	InitialRun bool
 
+	// Reports are the report files, directories or glob patterns.
+	Reports []string
+
+	// ReportsDir is a reports directory, which is found after Reports.
+	//
+	// Deprecated: Use Reports.
 	ReportsDir string
+
+	// Include and Exclude are glob patterns to filter the found report
+	// files. Patterns without a slash match the file name, other patterns
+	// match the path relative to the report path.
+	Include []string
+	Exclude []string
+
+	// InvalidReports is the policy for XML report files that are not valid
+	// JUnit XML. If empty, InvalidReportsOmit is used.
+	InvalidReports InvalidReports
+
 	MaxReports int
 }
 
+// Bundle writes the zstd compressed tarball of the report files into w. The
+// reports are streamed from disk, so the memory usage does not depend on the
+// size of the reports.
 func (c *Collector) Bundle(o BundleOptions, w io.Writer) error {
-	dir := o.ReportsDir
-
 	maxReports := o.MaxReports
 	if maxReports == 0 {
 		maxReports = DefaulMaxReports
 	}
 
-	c.log.Debug("read file reports", "dir", dir, "max", maxReports)
-	files, err := readReports(dir, maxReports)
+	c.log.Debug("find file reports", "reports", o.Reports, "max", maxReports)
+	paths, err := c.findReports(o, maxReports)
+	if err != nil {
+		return fmt.Errorf("failed to find reports (%q): %w", o.Reports, err)
+	}
+
+	reports, err := c.scanReports(paths, o.InvalidReports)
 	if err != nil {
-		return fmt.Errorf("failed to read reports (%q): %w", dir, err)
+		return fmt.Errorf("failed to read reports (%q): %w", o.Reports, err)
 	}
 
+	files := reportFiles(reports)
+	reportEntries := files
+
 	if len(files) == 0 {
-		c.log.Warn("no file reports found for bundle", "reports", dir)
+		c.log.Warn("no file reports found for bundle", "reports", o.Reports)
 		return nil
 	}
 
+	extra := make(map[string][]byte)
+
 	if o.InitialRun {
 		// Add CODEOWNERS file to the initial run only. This avoids storing the
 		// same information in each run bundle file.
-		if err := c.addCodeOwners(&files); err != nil {
+		if err := c.addCodeOwners(&extra); err != nil {
 			return fmt.Errorf("failed to add CODEOWNERS: %w", err)
 		}
 
-		if err := c.addGitSummary(&files); err != nil {
+		if err := c.addGitSummary(&extra); err != nil {
 			return fmt.Errorf("failed to add git summary: %w", err)
 		}
 	}
 
-	for name, content := range files {
-		c.log.Debug("add tar file", "name", name, "size", len(content))
+	names := slices.Sorted(maps.Keys(extra))
+	for _, name := range names {
+		content := extra[name]
+		files = append(files, tar.File{
+			Name: name,
+			Size: int64(len(content)),
+			Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(content)), nil
+			},
+		})
+	}
+
+	// Add the manifest last, since it contains the checksums of the written
+	// reports.
+	files = append(files, manifestFile(func() BundleManifest {
+		return c.newBundleManifest(reports, reportEntries)
+	}))
+
+	for _, f := range files {
+		c.log.Debug("add tar file", "name", f.Name, "size", f.Size)
+	}
+
+	z, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
 	}
 
-	var raw bytes.Buffer
-	if err := tar.Create(files, &raw); err != nil {
+	raw := &countWriter{w: z}
+	if err := tar.Stream(files, raw); err != nil {
+		// Release the encoder, the compressed data is incomplete anyway.
+		z.Close()
 		return fmt.Errorf("failed to create tarball: %w", err)
 	}
 
+	if err := z.Close(); err != nil {
+		return fmt.Errorf("failed to compress tarball: %w", err)
+	}
+
 	c.log.Info("tarball created",
 		"files", len(files),
-		"rawSize", raw.Len(),
+		"rawSize", raw.n,
 	)
 
-	if err := zstd.Compress(&raw, w); err != nil {
-		return fmt.Errorf("failed to compress tarball: %w", err)
-	}
-
 	return nil
 }
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
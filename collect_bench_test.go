@@ -0,0 +1,109 @@
+//go:build unix
+
+package record
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// BenchmarkBundle bundles a generated report set and reports the peak RSS of
+// the process. The total report size (in MB) defaults to a size that fits CI
+// runners and can be raised with RECORD_BENCH_REPORTS_MB, e.g.:
+//
+//	RECORD_BENCH_REPORTS_MB=4096 go test -run=^$ -bench=Bundle -benchtime=1x
+func BenchmarkBundle(b *testing.B) {
+	totalMB := 64
+	if val := os.Getenv("RECORD_BENCH_REPORTS_MB"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			b.Fatalf("invalid RECORD_BENCH_REPORTS_MB: %s", err)
+		}
+		totalMB = n
+	}
+
+	const fileMB = 16
+	count := max(totalMB/fileMB, 1)
+
+	dir := b.TempDir()
+	for i := 0; i < count; i++ {
+		writeReport(b, filepath.Join(dir, fmt.Sprintf("e2e-%d.xml", i)), fileMB<<20, i)
+	}
+
+	c := &Collector{
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	o := BundleOptions{
+		Reports:    []string{dir},
+		MaxReports: count,
+	}
+
+	b.SetBytes(int64(count) * fileMB << 20)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := c.Bundle(o, io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+	b.ReportMetric(peakRSS(b)/(1<<20), "peak-rss-MB")
+}
+
+// writeReport writes a JUnit report with unique test cases of the given size.
+func writeReport(b *testing.B, file string, size int, seed int) {
+	b.Helper()
+
+	f, err := os.Create(file)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	buf.WriteString("<testsuites>\n<testsuite name=\"e2e\">\n")
+
+	for i := 0; buf.Len() < size; i++ {
+		fmt.Fprintf(&buf, "<testcase classname=\"pkg%d.Suite\" name=\"test %d-%d\" time=\"%d.%03d\"/>\n",
+			seed, seed, i, i%7, (i*31)%1000)
+
+		if buf.Len() > 1<<20 {
+			if _, err := f.Write(buf.Bytes()); err != nil {
+				b.Fatal(err)
+			}
+			size -= buf.Len()
+			buf.Reset()
+		}
+	}
+
+	buf.WriteString("</testsuite>\n</testsuites>\n")
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// peakRSS returns the maximum resident set size of the process in bytes.
+func peakRSS(b *testing.B) float64 {
+	b.Helper()
+
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		b.Fatal(err)
+	}
+
+	// Linux reports kilobytes, darwin reports bytes.
+	if runtime.GOOS == "darwin" {
+		return float64(ru.Maxrss)
+	}
+	return float64(ru.Maxrss) * 1024
+}
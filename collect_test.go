@@ -55,7 +55,7 @@ func TestCollectorAddsCommitFiles(t *testing.T) {
 			defer srv.Close()
 
 			options := UploadOptions{
-				Reports: "testdata/github/reports",
+				Reports: []string{"testdata/github/reports"},
 				Repo:    "testdata/github/repo",
 			}
 
@@ -75,7 +75,7 @@ func TestCollectorAddsCommitFiles(t *testing.T) {
 			var data bytes.Buffer
 			err = collector.Bundle(BundleOptions{
 				InitialRun: tt.created,
-				ReportsDir: options.Reports,
+				Reports:    options.Reports,
 				MaxReports: options.MaxReports,
 			}, &data)
 			if !assert.NoError(err) {
@@ -112,3 +112,38 @@ func TestCollectorAddsCommitFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestBundleReportsDir(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	collector, err := NewCollector(l, "testdata/github/repo", srv.Env)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var data bytes.Buffer
+	err = collector.Bundle(BundleOptions{
+		ReportsDir: "testdata/github/reports",
+	}, &data)
+	if !assert.NoError(err) {
+		return
+	}
+
+	info, err := InspectBundle(&data)
+	if !assert.NoError(err) || !assert.NotNil(info.Manifest) {
+		return
+	}
+
+	var paths []string
+	for _, f := range info.Manifest.Files {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal([]string{
+		"testdata/github/reports/e2e-1.xml",
+		"testdata/github/reports/e2e-2.xml",
+	}, paths)
+}
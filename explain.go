@@ -0,0 +1,171 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Reasons of the explain report, unless the API returns a reason.
+const (
+	reasonPredicted    = "predicted"
+	reasonNotPredicted = "not predicted"
+	reasonRelated      = "related to changed files"
+	reasonNotRelated   = "not related to changed files"
+	reasonAll          = "fallback to all tests"
+)
+
+// explanation explains the selection of a test.
+type explanation struct {
+	Path     string `json:"path"`
+	Selected bool   `json:"selected"`
+	Reason   string `json:"reason"`
+
+	Score      *float64 `json:"score,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	Duration   *float64 `json:"duration,omitempty"`
+
+	// Changes are the changed files related to the test by the local
+	// heuristic. They are only set for the local strategy, since the API
+	// does not return them.
+	Changes []string `json:"changes,omitempty"`
+}
+
+type explainReport struct {
+	Strategy string        `json:"strategy"`
+	Changes  []string      `json:"changes"`
+	Tests    []explanation `json:"tests"`
+}
+
+// explainer records why tests are not selected while the predicted tests
+// pass the API, limits, fallback and shards.
+type explainer struct {
+	order   []string
+	tests   map[string]predictedTest
+	reasons map[string]string
+}
+
+func newExplainer(inputs []string) *explainer {
+	e := &explainer{
+		tests:   make(map[string]predictedTest),
+		reasons: make(map[string]string),
+	}
+	for _, path := range inputs {
+		e.add(predictedTest{Path: path})
+	}
+	return e
+}
+
+func (e *explainer) add(t predictedTest) {
+	if _, ok := e.tests[t.Path]; !ok {
+		e.order = append(e.order, t.Path)
+	}
+	e.tests[t.Path] = t
+}
+
+// exclude records the reason of the tests that are not selected anymore.
+func (e *explainer) exclude(selected []predictedTest, reason string) {
+	keep := make(map[string]bool)
+	for _, t := range selected {
+		e.add(t)
+		keep[t.Path] = true
+	}
+
+	for _, path := range e.order {
+		if _, ok := e.reasons[path]; !ok && !keep[path] {
+			e.reasons[path] = reason
+		}
+	}
+}
+
+func (e *explainer) drop(dropped []droppedTest) {
+	for _, d := range dropped {
+		e.add(d.predictedTest)
+		e.reasons[d.Path] = d.Reason
+	}
+}
+
+// report returns the explanation of each test in input order. The tests
+// without an exclusion reason are selected.
+func (e *explainer) report(strategy string, changes []string) explainReport {
+	r := explainReport{
+		Strategy: strategy,
+		Changes:  changes,
+	}
+
+	for _, path := range e.order {
+		t := e.tests[path]
+
+		x := explanation{
+			Path:       path,
+			Reason:     e.reasons[path],
+			Score:      t.Score,
+			Confidence: t.Confidence,
+			Duration:   t.Duration,
+		}
+
+		if x.Reason == "" {
+			x.Selected = true
+			x.Reason = t.Reason
+		}
+		if x.Reason == "" {
+			switch strategy {
+			case StrategyAPI:
+				x.Reason = reasonPredicted
+			case StrategyLocal:
+				x.Reason = reasonRelated
+			default:
+				x.Reason = reasonAll
+			}
+		}
+
+		if strategy == StrategyLocal {
+			for _, c := range changes {
+				if relatedTest(path, c) {
+					x.Changes = append(x.Changes, c)
+				}
+			}
+		}
+
+		r.Tests = append(r.Tests, x)
+	}
+
+	return r
+}
+
+// writeExplainFile writes the report as JSON to the file.
+func writeExplainFile(r explainReport, file string) error {
+	buf, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, append(buf, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write explain report: %w", err)
+	}
+	return nil
+}
+
+// writeExplainTable writes the report as a table to w.
+func writeExplainTable(r explainReport, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "strategy: %s\n", r.Strategy)
+	fmt.Fprintln(tw, "TEST\tSELECTED\tREASON\tSCORE\tCHANGES")
+
+	for _, x := range r.Tests {
+		score := "-"
+		if x.Score != nil {
+			score = strconv.FormatFloat(*x.Score, 'g', 3, 64)
+		}
+		changes := "-"
+		if len(x.Changes) > 0 {
+			changes = strings.Join(x.Changes, ",")
+		}
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\t%s\n", x.Path, x.Selected, x.Reason, score, changes)
+	}
+
+	return tw.Flush()
+}
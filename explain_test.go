@@ -0,0 +1,175 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestPredictExplain(t *testing.T) {
+	var tests = []struct {
+		name     string
+		options  PredictOptions
+		handler  http.HandlerFunc
+		stdin    string
+		expected explainReport
+	}{
+		{
+			name: "api",
+			options: PredictOptions{
+				Budget: 10 * time.Second,
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"testFiles": [
+					{"path": "TestA", "duration": 4, "score": 0.9, "reason": "covers changed files"},
+					{"path": "TestCodeowners", "duration": 5},
+					{"path": "TestC", "duration": 8}
+				]}`))
+			},
+			stdin: "TestA\nTestB\nTestCodeowners\nTestC\n",
+			expected: explainReport{
+				Strategy: StrategyAPI,
+				Changes:  []string{".github/CODEOWNERS"},
+				Tests: []explanation{
+					{Path: "TestA", Selected: true, Reason: "covers changed files", Score: float(0.9), Duration: float(4)},
+					{Path: "TestB", Reason: reasonNotPredicted},
+					{Path: "TestCodeowners", Selected: true, Reason: reasonPredicted, Duration: float(5)},
+					{Path: "TestC", Reason: dropOverBudget, Duration: float(8)},
+				},
+			},
+		},
+		{
+			name: "shard",
+			options: PredictOptions{
+				ShardIndex: 2,
+				ShardTotal: 2,
+			},
+			stdin: "TestA\nTestB\n",
+			expected: explainReport{
+				Strategy: StrategyAPI,
+				Changes:  []string{".github/CODEOWNERS"},
+				Tests: []explanation{
+					{Path: "TestA", Reason: "not in shard 2/2"},
+					{Path: "TestB", Selected: true, Reason: reasonPredicted},
+				},
+			},
+		},
+		{
+			name: "local",
+			options: PredictOptions{
+				Fallback: FallbackLocal,
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+			stdin: "TestA\nTestCodeowners\n",
+			expected: explainReport{
+				Strategy: StrategyLocal,
+				Changes:  []string{".github/CODEOWNERS"},
+				Tests: []explanation{
+					{Path: "TestA", Reason: reasonNotRelated},
+					{Path: "TestCodeowners", Selected: true, Reason: reasonRelated, Changes: []string{".github/CODEOWNERS"}},
+				},
+			},
+		},
+		{
+			name: "all",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+			stdin: "TestA\n",
+			expected: explainReport{
+				Strategy: StrategyAll,
+				Changes:  []string{".github/CODEOWNERS"},
+				Tests: []explanation{
+					{Path: "TestA", Selected: true, Reason: reasonAll},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slogt.New(t)
+			assert := assert.New(t)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			if tt.handler != nil {
+				srv.Handlers.Predict = tt.handler
+			}
+
+			file := filepath.Join(t.TempDir(), "explain.json")
+
+			var out bytes.Buffer
+			opt := tt.options
+			opt.Repo = "testdata/feature/repo"
+			opt.Runner = "go-test"
+			opt.ExplainFile = file
+			opt.Stdin = strings.NewReader(tt.stdin)
+			opt.Stdout = &out
+
+			err := Predict(l, srv.Env, opt)
+			if !assert.NoError(err) {
+				return
+			}
+
+			buf, err := os.ReadFile(file)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var report explainReport
+			assert.NoError(json.Unmarshal(buf, &report))
+			assert.Equal(tt.expected, report)
+		})
+	}
+}
+
+func TestPredictExplainStderr(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	srv.Handlers.Predict = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"testFiles": [{"path": "TestCodeowners", "score": 0.75}]}`))
+	}
+
+	var out, stderr bytes.Buffer
+	opt := PredictOptions{
+		Repo:    "testdata/feature/repo",
+		Runner:  "go-test",
+		Explain: true,
+		Stdin:   strings.NewReader("TestA\nTestCodeowners\n"),
+		Stdout:  &out,
+		Stderr:  &stderr,
+	}
+
+	err := Predict(l, srv.Env, opt)
+	if !assert.NoError(err) {
+		return
+	}
+
+	expected := `strategy: api
+TEST            SELECTED  REASON         SCORE  CHANGES
+TestA           false     not predicted  -      -
+TestCodeowners  true      predicted      0.75   -
+`
+	assert.Equal(expected, stderr.String())
+	assert.Equal("^(TestCodeowners)$", out.String())
+}
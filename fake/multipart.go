@@ -0,0 +1,48 @@
+package fake
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+)
+
+// The multipart requests mirror the unexported types of the record package.
+
+type multipartCreateRequest struct {
+	Size     int64 `json:"size"`
+	PartSize int64 `json:"partSize"`
+}
+
+type multipartCreateResponse struct {
+	Id       string `json:"id"`
+	UploadId string `json:"uploadId"`
+}
+
+type multipartPartsRequest struct {
+	PartNumbers []int `json:"partNumbers"`
+}
+
+type multipartPart struct {
+	PartNumber int    `json:"partNumber"`
+	Url        string `json:"url,omitempty"`
+	ETag       string `json:"etag,omitempty"`
+}
+
+type multipartPartsResponse struct {
+	Parts []multipartPart `json:"parts"`
+}
+
+type multipartCompleteRequest struct {
+	UploadId string          `json:"uploadId"`
+	Parts    []multipartPart `json:"parts"`
+}
+
+func multipartUploadId(fileId int) string {
+	return fmt.Sprintf("upload-%d", fileId)
+}
+
+// etag returns the quoted MD5 hash of the part like S3.
+func etag(body []byte) string {
+	sum := md5.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
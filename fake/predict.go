@@ -0,0 +1,45 @@
+package fake
+
+import "github.com/testlabtools/record/client"
+
+// PredictLimits are the selection limits of a predict request.
+type PredictLimits struct {
+	// Budget is the estimated runtime budget in seconds.
+	Budget *float64 `json:"budget,omitempty"`
+
+	// Confidence is the minimum confidence of a selected test.
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+type predictTestFile struct {
+	Path     string   `json:"path"`
+	Duration *float64 `json:"duration,omitempty"`
+}
+
+type predictResponse struct {
+	TestFiles []predictTestFile `json:"testFiles"`
+}
+
+// predictWithin returns the test files with their durations in request
+// order. If a budget is set, tests exceeding the remaining budget are
+// omitted.
+func predictWithin(files []client.PredictTestFile, durations map[string]float64, limits PredictLimits) []predictTestFile {
+	var total float64
+	var out []predictTestFile
+
+	for _, f := range files {
+		tf := predictTestFile{Path: f.Path}
+
+		if d, ok := durations[f.Path]; ok {
+			if limits.Budget != nil && total+d > *limits.Budget {
+				continue
+			}
+			total += d
+			tf.Duration = &d
+		}
+
+		out = append(out, tf)
+	}
+
+	return out
+}
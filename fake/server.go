@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,6 +31,14 @@ type FakeHandlers struct {
 
 	PutS3File http.HandlerFunc
 
+	CreateMultipart http.HandlerFunc
+
+	PostPartUrls http.HandlerFunc
+
+	PutS3Part http.HandlerFunc
+
+	CompleteMultipart http.HandlerFunc
+
 	NotFound http.HandlerFunc
 }
 
@@ -41,12 +50,34 @@ type FakeServer struct {
 
 	Env map[string]string
 
+	// RunIdEnv is the env var name holding the CI run id of the provider.
+	RunIdEnv string
+
 	Runs     map[string]client.CIRunRequest
 	Files    [][]byte
 	fileUrls []string
 	status   map[int]client.FileUploadStatus
 
 	Predicts []client.PredictRequest
+
+	// PredictLimits are the limits of the predict requests.
+	PredictLimits []PredictLimits
+
+	// PredictEncodings are the content encodings of the predict requests.
+	PredictEncodings []string
+
+	// Durations are the test durations in seconds returned by the predict
+	// handler. Tests exceeding the budget of the request are not returned.
+	Durations map[string]float64
+
+	// Parts are the uploaded parts of multipart uploads by file id and part
+	// number. Completed uploads are appended to Files.
+	Parts map[int]map[int][]byte
+
+	// PartUploads counts the part uploads, including replaced parts.
+	PartUploads int
+
+	mu sync.Mutex
 }
 
 func (s *FakeServer) Close() {
@@ -75,11 +106,14 @@ func NewServer(t *testing.T, l *slog.Logger, ci client.CIProviderName) *FakeServ
 
 		Runs:   make(map[string]client.CreateRunJSONRequestBody),
 		status: make(map[int]client.FileUploadStatus),
+		Parts:  make(map[int]map[int][]byte),
 	}
 
 	switch ci {
 	case client.Github:
 		fs.useGitHub()
+	case client.Gitlab:
+		fs.useGitLab()
 	default:
 		panic(fmt.Sprintf("unknown CI provider name: %q", ci))
 	}
@@ -155,10 +189,7 @@ func NewServer(t *testing.T, l *slog.Logger, ci client.CIProviderName) *FakeServ
 	mux.HandleFunc("POST /api/v1/runs/{runId}/files/upload", secure(&h.PostFileUpload))
 
 	h.PatchFileInfo = func(w http.ResponseWriter, r *http.Request) {
-		fileId, err := strconv.Atoi(r.PathValue("fileId"))
-		if err != nil {
-			panic(err)
-		}
+		fileId := mustAtoi(r.PathValue("fileId"))
 
 		var info client.UpdateRunFileInfoJSONBody
 		mustDecode(r.Body, &info)
@@ -172,10 +203,19 @@ func NewServer(t *testing.T, l *slog.Logger, ci client.CIProviderName) *FakeServ
 	mux.HandleFunc("PATCH /api/v1/runs/{runId}/files/{fileId}", secure(&h.PatchFileInfo))
 
 	h.Predict = func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+
 		var req client.PredictRequest
-		mustDecode(r.Body, &req)
+		mustDecode(io.NopCloser(bytes.NewReader(body)), &req)
+
+		var limits PredictLimits
+		mustDecode(io.NopCloser(bytes.NewReader(body)), &limits)
 
 		fs.Predicts = append(fs.Predicts, req)
+		fs.PredictLimits = append(fs.PredictLimits, limits)
 
 		assert.NotEmpty(req.TestFiles, "TestFiles")
 		assert.NotEmpty(req.CiRun.GitRepo, "GITHUB_REPO")
@@ -185,13 +225,41 @@ func NewServer(t *testing.T, l *slog.Logger, ci client.CIProviderName) *FakeServ
 		l.Debug("got predict test files", "testFiles", req.TestFiles)
 
 		w.WriteHeader(http.StatusOK)
-		resp := client.PredictResponse{
-			TestFiles: req.TestFiles,
+		resp := predictResponse{
+			TestFiles: predictWithin(req.TestFiles, fs.Durations, limits),
 		}
 		mustEncode(w, resp)
 	}
 
-	mux.HandleFunc("POST /api/v1/predict", secure(&h.Predict))
+	// decompress decodes zstd compressed request bodies.
+	decompress := func(handler *http.HandlerFunc) *http.HandlerFunc {
+		var h http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+			encoding := r.Header.Get("Content-Encoding")
+
+			fs.mu.Lock()
+			fs.PredictEncodings = append(fs.PredictEncodings, encoding)
+			fs.mu.Unlock()
+
+			switch encoding {
+			case "":
+			case "zstd":
+				var buf bytes.Buffer
+				if err := zstd.Decompress(r.Body, &buf); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(&buf)
+			default:
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+
+			(*handler)(w, r)
+		}
+		return &h
+	}
+
+	mux.HandleFunc("POST /api/v1/predict", secure(decompress(&h.Predict)))
 
 	h.PutS3File = func(w http.ResponseWriter, r *http.Request) {
 		body, _ := io.ReadAll(r.Body)
@@ -201,6 +269,93 @@ func NewServer(t *testing.T, l *slog.Logger, ci client.CIProviderName) *FakeServ
 
 	mux.HandleFunc("PUT /s3/files/{fileId}", log(&h.PutS3File))
 
+	h.CreateMultipart = func(w http.ResponseWriter, r *http.Request) {
+		var req multipartCreateRequest
+		mustDecode(r.Body, &req)
+
+		assert.NotZero(req.Size, "size")
+		assert.NotZero(req.PartSize, "partSize")
+
+		fs.mu.Lock()
+		id := len(fs.fileUrls) + 1
+		fs.fileUrls = append(fs.fileUrls, fmt.Sprintf("%s/s3/multipart/%d", server.URL, id))
+		fs.Parts[id] = make(map[int][]byte)
+		fs.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		mustEncode(w, multipartCreateResponse{
+			Id:       fmt.Sprint(id),
+			UploadId: multipartUploadId(id),
+		})
+	}
+
+	mux.HandleFunc("POST /api/v1/runs/{runId}/files/multipart", secure(&h.CreateMultipart))
+
+	h.PostPartUrls = func(w http.ResponseWriter, r *http.Request) {
+		fileId := mustAtoi(r.PathValue("fileId"))
+
+		var req multipartPartsRequest
+		mustDecode(r.Body, &req)
+
+		var resp multipartPartsResponse
+		for _, n := range req.PartNumbers {
+			resp.Parts = append(resp.Parts, multipartPart{
+				PartNumber: n,
+				Url:        fmt.Sprintf("%s/s3/multipart/%d/%d", server.URL, fileId, n),
+			})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		mustEncode(w, resp)
+	}
+
+	mux.HandleFunc("POST /api/v1/runs/{runId}/files/{fileId}/multipart/parts", secure(&h.PostPartUrls))
+
+	h.PutS3Part = func(w http.ResponseWriter, r *http.Request) {
+		fileId := mustAtoi(r.PathValue("fileId"))
+		n := mustAtoi(r.PathValue("partNumber"))
+
+		body, _ := io.ReadAll(r.Body)
+
+		fs.mu.Lock()
+		fs.Parts[fileId][n] = body
+		fs.PartUploads++
+		fs.mu.Unlock()
+
+		w.Header().Set("ETag", etag(body))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	mux.HandleFunc("PUT /s3/multipart/{fileId}/{partNumber}", log(&h.PutS3Part))
+
+	h.CompleteMultipart = func(w http.ResponseWriter, r *http.Request) {
+		fileId := mustAtoi(r.PathValue("fileId"))
+
+		var req multipartCompleteRequest
+		mustDecode(r.Body, &req)
+
+		assert.Equal(multipartUploadId(fileId), req.UploadId, "uploadId")
+
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+
+		parts := fs.Parts[fileId]
+		assert.Len(req.Parts, len(parts), "completed parts")
+
+		var file []byte
+		for i, p := range req.Parts {
+			assert.Equal(i+1, p.PartNumber, "parts must be in order")
+			assert.Equal(etag(parts[p.PartNumber]), p.ETag, "etag of part %d", p.PartNumber)
+			file = append(file, parts[p.PartNumber]...)
+		}
+		fs.Files = append(fs.Files, file)
+
+		w.WriteHeader(http.StatusOK)
+		mustEncode(w, struct{}{})
+	}
+
+	mux.HandleFunc("POST /api/v1/runs/{runId}/files/{fileId}/multipart/complete", secure(&h.CompleteMultipart))
+
 	h.NotFound = func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -213,6 +368,8 @@ func NewServer(t *testing.T, l *slog.Logger, ci client.CIProviderName) *FakeServ
 }
 
 func (s *FakeServer) useGitHub() {
+	s.RunIdEnv = "GITHUB_RUN_ID"
+
 	s.Env["GITHUB_ACTIONS"] = "true"
 	s.Env["GITHUB_ACTOR"] = "smvv"
 	s.Env["GITHUB_REF"] = "refs/heads/feature-branch-1"
@@ -225,6 +382,30 @@ func (s *FakeServer) useGitHub() {
 	s.Env["GITHUB_SHA"] = "ffac537e6cbbf934b08745a378932722df287a53"
 }
 
+func (s *FakeServer) useGitLab() {
+	s.RunIdEnv = "CI_PIPELINE_ID"
+
+	s.Env["GITLAB_CI"] = "true"
+	s.Env["GITLAB_USER_LOGIN"] = "smvv"
+	s.Env["CI_COMMIT_REF_NAME"] = "feature-branch-1"
+	s.Env["CI_COMMIT_SHA"] = "ffac537e6cbbf934b08745a378932722df287a53"
+	s.Env["CI_DEFAULT_BRANCH"] = "main"
+	s.Env["CI_JOB_ID"] = "8143541276"
+	s.Env["CI_JOB_NAME"] = "e2e"
+	s.Env["CI_MERGE_REQUEST_IID"] = "42"
+	s.Env["CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"] = "feature-branch-1"
+	s.Env["CI_MERGE_REQUEST_TARGET_BRANCH_NAME"] = "main"
+	s.Env["CI_PIPELINE_ID"] = "1538227016"
+	s.Env["CI_PIPELINE_IID"] = "3"
+	s.Env["CI_PIPELINE_SOURCE"] = "merge_request_event"
+	s.Env["CI_PROJECT_PATH"] = "octocat/Hello-World"
+}
+
+// RunKey returns the key of the run created with the server env in Runs.
+func (s *FakeServer) RunKey() string {
+	return s.Env[s.RunIdEnv] + "-" + s.Env["TESTLAB_GROUP"]
+}
+
 func (s *FakeServer) ExtractTar(i int) (map[string][]byte, error) {
 	file := s.Files[i]
 	r := bytes.NewReader(file)
@@ -237,6 +418,14 @@ func (s *FakeServer) ExtractTar(i int) (map[string][]byte, error) {
 	return tar.Extract(&buf)
 }
 
+func mustAtoi(s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
 func mustDecode(r io.ReadCloser, v interface{}) {
 	defer r.Close()
 	err := json.NewDecoder(r).Decode(&v)
@@ -0,0 +1,234 @@
+package record
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/testlabtools/record/git"
+	"github.com/testlabtools/record/runner"
+)
+
+// Fallback decides which tests are selected if the prediction fails.
+type Fallback string
+
+const (
+	// FallbackAll selects all input tests. This is the default.
+	FallbackAll Fallback = "all"
+
+	// FallbackLocal selects the input tests related to the changed files of
+	// the git repo. If no test is related, all input tests are selected.
+	FallbackLocal Fallback = "local"
+
+	// FallbackNone fails the prediction.
+	FallbackNone Fallback = "none"
+)
+
+// Strategies that select the predicted tests.
+const (
+	StrategyAPI   = "api"
+	StrategyLocal = "local"
+	StrategyAll   = "all"
+)
+
+func validateFallback(f Fallback) error {
+	switch f {
+	case "", FallbackAll, FallbackLocal, FallbackNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown fallback: %q", f)
+	}
+}
+
+// fallbackTests selects the tests of the fallback after the prediction failed
+// with err. It returns the tests and the strategy that selected them.
+func fallbackTests(l *slog.Logger, o PredictOptions, run runner.Parser, err error) ([]predictedTest, string, error) {
+	files := run.Files()
+
+	switch o.Fallback {
+	case FallbackNone:
+		return nil, "", fmt.Errorf("failed to predict: %w", err)
+
+	case FallbackLocal:
+		changes, err := changedFiles(o)
+		if err != nil {
+			l.Warn("failed to get changed files for local fallback", "err", err)
+			break
+		}
+
+		tests := localTests(changes, files)
+		if len(tests) > 0 {
+			l.Warn("fallback to local test selection", "changes", len(changes), "files", len(tests))
+			return tests, StrategyLocal, nil
+		}
+
+		l.Warn("no tests related to changed files", "changes", len(changes))
+	}
+
+	// Fallback to original input.
+	l.Warn("fallback to original test input", "files", len(files))
+
+	var tests []predictedTest
+	for _, f := range files {
+		tests = append(tests, predictedTest{Path: f})
+	}
+	return tests, StrategyAll, nil
+}
+
+// changedFiles returns the changed files of the git repo relative to the
+// workdir, since the test files of the runners are relative to the workdir.
+func changedFiles(o PredictOptions) ([]string, error) {
+	repo := git.NewRepo(o.Repo)
+	if !repo.Exists() {
+		return nil, fmt.Errorf("git repo does not exist: %q", o.Repo)
+	}
+
+	ds, err := repo.DiffStat("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := filepath.Abs(o.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	wd := o.WorkDir
+	if wd == "" {
+		wd = dir
+	}
+
+	var changes []string
+	for _, c := range ds.Changes {
+		file := filepath.Join(dir, filepath.FromSlash(c.Name))
+		rel, err := filepath.Rel(wd, file)
+		if err != nil {
+			rel = c.Name
+		}
+		changes = append(changes, filepath.ToSlash(rel))
+	}
+
+	return changes, nil
+}
+
+// localTests returns the tests related to the changed files in input order.
+// A test is related to a change if it is the changed file, if it is in the
+// same directory (or package) or if both have the same name without test
+// affixes and extensions, e.g. `foo.go` and `foo_test.go` or `Foo.ts` and
+// `Foo.test.ts`.
+func localTests(changes []string, files []string) []predictedTest {
+	var tests []predictedTest
+
+	for _, f := range files {
+		for _, c := range changes {
+			if relatedTest(f, c) {
+				tests = append(tests, predictedTest{Path: f})
+				break
+			}
+		}
+	}
+
+	return tests
+}
+
+func relatedTest(test, change string) bool {
+	// Test files of parsers are relative to the workdir with a leading slash.
+	test = strings.TrimPrefix(test, "/")
+
+	if test == change {
+		return true
+	}
+
+	name, isTest := baseName(change)
+	if name == "" || isTest {
+		// Changed tests only select themselves.
+		return false
+	}
+
+	// Test identities like Go tests `pkg/TestName` can be prefixed by the
+	// module path.
+	dir := path.Dir(change)
+	testDir := path.Dir(test)
+	if dir != "." && !strings.HasPrefix(dir, "..") && (testDir == dir || strings.HasSuffix(testDir, "/"+dir)) {
+		return true
+	}
+
+	testName, _ := baseName(test)
+	return name == testName
+}
+
+var (
+	testPrefixes   = []string{"test_", "test-"}
+	testSuffixes   = []string{"_tests", "-tests", "_test", "-test", "_spec", "-spec"}
+	testExtensions = []string{"test", "spec", "cy", "e2e"}
+
+	// Affixes of camel case names like `TestFoo` or `FooTest`, which need an
+	// upper case letter at the word boundary.
+	testCamelPrefixes = []string{"Test"}
+	testCamelSuffixes = []string{"Tests", "Test", "Spec"}
+)
+
+// baseName returns the lower case file name without extensions and test
+// affixes. It also returns true if the name had a test affix. Affixes need a
+// separator or a camel case boundary, so `latest.go` is not a test.
+func baseName(file string) (string, bool) {
+	name := path.Base(file)
+
+	// Omit the extensions, e.g. `.test.ts`.
+	isTest := false
+	if i := strings.Index(name, "."); i > 0 {
+		for _, ext := range strings.Split(name[i+1:], ".") {
+			isTest = isTest || slices.Contains(testExtensions, ext)
+		}
+		name = name[:i]
+	}
+
+	if rest, ok := cutTestPrefix(name); ok {
+		name = rest
+		isTest = true
+	}
+	if rest, ok := cutTestSuffix(name); ok {
+		name = rest
+		isTest = true
+	}
+
+	name = strings.ToLower(name)
+	name = strings.TrimFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return name, isTest
+}
+
+func cutTestPrefix(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	for _, p := range testPrefixes {
+		if strings.HasPrefix(lower, p) && len(name) > len(p) {
+			return name[len(p):], true
+		}
+	}
+	for _, p := range testCamelPrefixes {
+		if rest, ok := strings.CutPrefix(name, p); ok && rest != "" && unicode.IsUpper(rune(rest[0])) {
+			return rest, true
+		}
+	}
+	return name, false
+}
+
+func cutTestSuffix(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	for _, s := range testSuffixes {
+		if strings.HasSuffix(lower, s) && len(name) > len(s) {
+			return name[:len(name)-len(s)], true
+		}
+	}
+	for _, s := range testCamelSuffixes {
+		if rest, ok := strings.CutSuffix(name, s); ok && rest != "" {
+			return rest, true
+		}
+	}
+	return name, false
+}
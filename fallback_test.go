@@ -0,0 +1,179 @@
+package record
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestLocalTests(t *testing.T) {
+	var tests = []struct {
+		name     string
+		changes  []string
+		files    []string
+		expected []string
+	}{
+		{
+			name:     "changed-test",
+			changes:  []string{"api/tests/user.test.ts"},
+			files:    []string{"/api/tests/user.test.ts", "/api/tests/order.test.ts"},
+			expected: []string{"/api/tests/user.test.ts"},
+		},
+		{
+			name:     "go-test-file",
+			changes:  []string{"foo.go"},
+			files:    []string{"/foo_test.go", "/bar_test.go"},
+			expected: []string{"/foo_test.go"},
+		},
+		{
+			name:     "ts-test-file",
+			changes:  []string{"src/Foo.ts"},
+			files:    []string{"/test/Foo.test.ts", "/test/Bar.spec.ts", "/e2e/foo.cy.ts"},
+			expected: []string{"/test/Foo.test.ts", "/e2e/foo.cy.ts"},
+		},
+		{
+			name:     "python-test-file",
+			changes:  []string{"app/models.py"},
+			files:    []string{"/tests/test_models.py", "/tests/test_views.py"},
+			expected: []string{"/tests/test_models.py"},
+		},
+		{
+			name:     "java-test-class",
+			changes:  []string{"src/main/java/org/app/Parser.java"},
+			files:    []string{"/src/test/java/org/app/ParserTest.java", "/src/test/java/org/app/LexerTest.java"},
+			expected: []string{"/src/test/java/org/app/ParserTest.java"},
+		},
+		{
+			name:     "same-directory",
+			changes:  []string{"service/db/conn.go"},
+			files:    []string{"/service/db/pool_test.go", "/service/api/conn_test.go", "/service/api_test.go"},
+			expected: []string{"/service/db/pool_test.go", "/service/api/conn_test.go"},
+		},
+		{
+			name:    "go-package",
+			changes: []string{"runner/jest.go"},
+			files: []string{
+				"github.com/testlabtools/record/runner/TestJVM",
+				"github.com/testlabtools/record/cmd/TestPredictCommand",
+			},
+			expected: []string{"github.com/testlabtools/record/runner/TestJVM"},
+		},
+		{
+			name:     "root-directory",
+			changes:  []string{"main.go", "../other/lib.go"},
+			files:    []string{"/root_test.go", "/lib/lib_test.go"},
+			expected: []string{"/lib/lib_test.go"},
+		},
+		{
+			name:     "test-in-name",
+			changes:  []string{"latest.go", "testing.go", "contest.go"},
+			files:    []string{"/la_test.go", "/latest_test.go", "/ing_test.go", "/testing_test.go", "/con_test.go", "/contest_test.go"},
+			expected: []string{"/latest_test.go", "/testing_test.go", "/contest_test.go"},
+		},
+		{
+			name:     "test-in-test-name",
+			changes:  []string{"src/Latest.ts", "src/Contest.ts"},
+			files:    []string{"/test/La.test.ts", "/test/LatestTest.ts", "/test/Con.spec.ts", "/test/ContestSpec.ts"},
+			expected: []string{"/test/LatestTest.ts", "/test/ContestSpec.ts"},
+		},
+		{
+			name:    "unrelated",
+			changes: []string{"README.md"},
+			files:   []string{"/foo_test.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, testPaths(localTests(tt.changes, tt.files)))
+		})
+	}
+}
+
+func TestPredictFallback(t *testing.T) {
+	var tests = []struct {
+		name     string
+		fallback Fallback
+		stdin    string
+		expected string
+		err      string
+	}{
+		{
+			name:     "default",
+			stdin:    "TestA\nTestCodeowners\n",
+			expected: "^(TestA|TestCodeowners)$",
+		},
+		{
+			name:     "all",
+			fallback: FallbackAll,
+			stdin:    "TestA\nTestCodeowners\n",
+			expected: "^(TestA|TestCodeowners)$",
+		},
+		{
+			// The feature repo changes the .github/CODEOWNERS file.
+			name:     "local",
+			fallback: FallbackLocal,
+			stdin:    "TestA\nTestCodeowners\n",
+			expected: "^(TestCodeowners)$",
+		},
+		{
+			name:     "local-unrelated",
+			fallback: FallbackLocal,
+			stdin:    "TestA\nTestB\n",
+			expected: "^(TestA|TestB)$",
+		},
+		{
+			name:     "none",
+			fallback: FallbackNone,
+			stdin:    "TestA\n",
+			err:      "failed to predict: predict tests returned invalid status code: 500",
+		},
+		{
+			name:     "unknown",
+			fallback: "some",
+			err:      `unknown fallback: "some"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slogt.New(t)
+			assert := assert.New(t)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			srv.Handlers.Predict = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			var out bytes.Buffer
+			opt := PredictOptions{
+				Repo:     "testdata/feature/repo",
+				Runner:   "go-test",
+				Fallback: tt.fallback,
+				Stdin:    strings.NewReader(tt.stdin),
+				Stdout:   &out,
+			}
+
+			// Disable HTTP retry logic.
+			opt.client = &http.Client{}
+
+			err := Predict(l, srv.Env, opt)
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tt.expected, out.String())
+		})
+	}
+}
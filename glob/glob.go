@@ -0,0 +1,133 @@
+package glob
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// HasMeta reports whether the pattern contains any glob meta characters.
+func HasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// Match reports whether the slash separated name matches the pattern. The
+// pattern uses the syntax of path.Match and additionally supports `**` as a
+// path segment, which matches zero or more directories.
+func Match(pattern, name string) (bool, error) {
+	return matchSegments(split(pattern), split(name))
+}
+
+func split(p string) []string {
+	p = path.Clean(filepath.ToSlash(p))
+	if p == "." {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		seg := pattern[0]
+
+		if seg == "**" {
+			// Collapse consecutive `**` segments.
+			rest := pattern[1:]
+			for len(rest) > 0 && rest[0] == "**" {
+				rest = rest[1:]
+			}
+			if len(rest) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(rest, name[i:])
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+
+		ok, err := path.Match(seg, name[0])
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %w", seg, err)
+		}
+		if !ok {
+			return false, nil
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+// Base returns the leading directory of the pattern that contains no glob
+// meta characters. This is the directory walked by Glob.
+func Base(pattern string) string {
+	segs := split(pattern)
+	abs := strings.HasPrefix(filepath.ToSlash(pattern), "/")
+
+	var base []string
+	for _, seg := range segs {
+		if HasMeta(seg) {
+			break
+		}
+		base = append(base, seg)
+	}
+
+	dir := strings.Join(base, "/")
+	if abs {
+		dir = "/" + strings.TrimPrefix(dir, "/")
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.FromSlash(dir)
+}
+
+// Glob returns the regular files matching the pattern in lexical order. A
+// missing base directory is not an error and returns no files.
+func Glob(pattern string) ([]string, error) {
+	// Validate the pattern before walking any directories.
+	if _, err := Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	base := Base(pattern)
+	if _, err := os.Stat(base); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var files []string
+
+	err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", p, err)
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		ok, err := Match(pattern, p)
+		if err != nil {
+			return err
+		}
+		if ok {
+			files = append(files, p)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
@@ -0,0 +1,130 @@
+package glob
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.xml", "e2e.xml", true},
+		{"*.xml", "reports/e2e.xml", false},
+		{"reports/*.xml", "reports/e2e.xml", true},
+		{"**/*.xml", "e2e.xml", true},
+		{"**/*.xml", "a/b/c/e2e.xml", true},
+		{"**/target/surefire-reports/*.xml", "svc/api/target/surefire-reports/TEST-Api.xml", true},
+		{"**/target/surefire-reports/*.xml", "svc/api/target/failsafe-reports/TEST-Api.xml", false},
+		{"reports/**", "reports/a/b.xml", true},
+		{"reports/**/**/b.xml", "reports/b.xml", true},
+		{"**/node_modules/**", "web/node_modules/jest/report.xml", true},
+		{"./reports/*.xml", "reports/e2e.xml", true},
+		{"/tmp/**/*.xml", "/tmp/reports/e2e.xml", true},
+		{"reports/**/", "reports/a/b.xml", true},
+		{"reports/**/", "other/a/b.xml", false},
+		{"**", "a/b.xml", true},
+		{"**/e2e.xml", "e2e.xml", true},
+		{"**/e2e.xml", "a/b/e2e.xml", true},
+		{"**/e2e.xml", "a/b/e2e.xml.bak", false},
+		{"**/e2e.xml", "a/b/xe2e.xml", false},
+		{"**.xml", "a/b.xml", false},
+		{"reports/**.xml", "reports/b.xml", true},
+		{"a/**/b/**/c.xml", "a/x/b/y/z/c.xml", true},
+		{"a/**/b/**/c.xml", "a/x/y/c.xml", false},
+		{`reports/\*.xml`, "reports/*.xml", true},
+		{`reports/\*.xml`, "reports/e2e.xml", false},
+		{`reports/e2e\[1\].xml`, "reports/e2e[1].xml", true},
+		{"reports/e2e-[12].xml", "reports/e2e-1.xml", true},
+		{"reports/e2e-[12].xml", "reports/e2e-3.xml", false},
+		{"reports/e2e-[^12].xml", "reports/e2e-3.xml", true},
+		{"reports/[a-c]*/*.xml", "reports/b2/e2e.xml", true},
+		{"reports/[a-c]*/*.xml", "reports/d2/e2e.xml", false},
+		{"reports/?2e.xml", "reports/e2e.xml", true},
+		{"reports/?2e.xml", "reports/a/2e.xml", false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.pattern, tt.name), func(t *testing.T) {
+			assert := assert.New(t)
+			ok, err := Match(tt.pattern, tt.name)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tt.match, ok)
+		})
+	}
+}
+
+func TestMatchInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"reports/[.xml", "**/[a-.xml", `reports/e2e.xml\`} {
+		t.Run(pattern, func(t *testing.T) {
+			_, err := Match(pattern, "reports/e2e.xml")
+			assert.ErrorContains(t, err, "invalid pattern")
+		})
+	}
+}
+
+func TestBase(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		base    string
+	}{
+		{"**/*.xml", "."},
+		{"reports/*.xml", "reports"},
+		{"./a/b/**/c/*.xml", "a/b"},
+		{"/tmp/reports/**", "/tmp/reports"},
+		{"reports", "reports"},
+		{"reports/**/", "reports"},
+		{`reports/\*.xml`, "reports"},
+		{"reports/e2e-[12].xml", "reports"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.base, Base(tt.pattern))
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		files   []string
+	}{
+		{
+			pattern: "../testdata/*/reports/*.xml",
+			files: []string{
+				"../testdata/basic/reports/e2e-1.xml",
+				"../testdata/basic/reports/e2e-2.xml",
+				"../testdata/github/reports/e2e-1.xml",
+				"../testdata/github/reports/e2e-2.xml",
+				"../testdata/invalid/reports/e2e-1.xml",
+				"../testdata/invalid/reports/e2e-2.xml",
+			},
+		},
+		{
+			pattern: "../testdata/**/e2e-2.xml",
+			files: []string{
+				"../testdata/basic/reports/e2e-2.xml",
+				"../testdata/github/reports/e2e-2.xml",
+				"../testdata/invalid/reports/e2e-2.xml",
+			},
+		},
+		{
+			pattern: "../testdata/unknown/**/*.xml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert := assert.New(t)
+			files, err := Glob(tt.pattern)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tt.files, files)
+		})
+	}
+}
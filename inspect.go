@@ -0,0 +1,96 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/testlabtools/record/tar"
+	"github.com/testlabtools/record/zstd"
+)
+
+// maxInspectSize limits the size of the bundle metadata files that are read
+// into memory while inspecting a bundle.
+const maxInspectSize = 64 << 20
+
+// BundleEntry is a file of an inspected bundle.
+type BundleEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+
+	// Path is the original report path of the file, if it is listed in the
+	// bundle manifest.
+	Path string `json:"path,omitempty"`
+}
+
+// BundleInfo is the content summary of a bundle.
+type BundleInfo struct {
+	Entries    []BundleEntry   `json:"entries"`
+	Manifest   *BundleManifest `json:"manifest,omitempty"`
+	GitSummary *GitSummary     `json:"gitSummary,omitempty"`
+	CodeOwners string          `json:"codeOwners,omitempty"`
+}
+
+// InspectBundle reads a zstd compressed bundle and returns its entries. Only
+// the bundle metadata files are kept in memory.
+func InspectBundle(r io.Reader) (*BundleInfo, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	info := &BundleInfo{}
+
+	err = tar.Walk(zr, func(name string, size int64, r io.Reader) error {
+		info.Entries = append(info.Entries, BundleEntry{
+			Name: name,
+			Size: size,
+		})
+
+		switch name {
+		case BundleManifestFileName:
+			var m BundleManifest
+			if err := decodeEntry(name, r, &m); err != nil {
+				return err
+			}
+			info.Manifest = &m
+		case GitSummaryFileName:
+			var summary GitSummary
+			if err := decodeEntry(name, r, &summary); err != nil {
+				return err
+			}
+			info.GitSummary = &summary
+		case "CODEOWNERS":
+			buf, err := io.ReadAll(io.LimitReader(r, maxInspectSize))
+			if err != nil {
+				return fmt.Errorf("failed to read %q: %w", name, err)
+			}
+			info.CodeOwners = string(buf)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect bundle: %w", err)
+	}
+
+	if info.Manifest != nil {
+		paths := make(map[string]string)
+		for _, f := range info.Manifest.Files {
+			paths[f.Name] = f.Path
+		}
+		for i, e := range info.Entries {
+			info.Entries[i].Path = paths[e.Name]
+		}
+	}
+
+	return info, nil
+}
+
+func decodeEntry(name string, r io.Reader, v interface{}) error {
+	if err := json.NewDecoder(io.LimitReader(r, maxInspectSize)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %q: %w", name, err)
+	}
+	return nil
+}
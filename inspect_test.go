@@ -0,0 +1,68 @@
+package record
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestInspectBundle(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	collector, err := NewCollector(l, "testdata/github/repo", srv.Env)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var data bytes.Buffer
+	err = collector.Bundle(BundleOptions{
+		InitialRun: true,
+		Reports:    []string{"testdata/github/reports"},
+	}, &data)
+	if !assert.NoError(err) {
+		return
+	}
+
+	info, err := InspectBundle(&data)
+	if !assert.NoError(err) {
+		return
+	}
+
+	expected := []BundleEntry{
+		{Name: "reports/1.xml", Size: 445, Path: "testdata/github/reports/e2e-1.xml"},
+		{Name: "reports/2.xml", Size: 445, Path: "testdata/github/reports/e2e-2.xml"},
+		{Name: "CODEOWNERS", Size: 32},
+		{Name: GitSummaryFileName},
+		{Name: BundleManifestFileName},
+	}
+
+	// Copy generated file sizes to stabilize test.
+	for i := range info.Entries {
+		if expected[i].Size == 0 {
+			assert.NotZero(info.Entries[i].Size)
+			expected[i].Size = info.Entries[i].Size
+		}
+	}
+	assert.Equal(expected, info.Entries)
+
+	assert.Equal("*.go @org/team1\ne2e/ @org/team2\n", info.CodeOwners)
+	if assert.NotNil(info.GitSummary) {
+		assert.NotEmpty(info.GitSummary.DiffStat.Hash)
+	}
+	if assert.NotNil(info.Manifest) {
+		assert.Len(info.Manifest.Files, 2)
+	}
+}
+
+func TestInspectInvalidBundle(t *testing.T) {
+	_, err := InspectBundle(bytes.NewReader([]byte("<testsuites/>")))
+	assert.ErrorContains(t, err, "failed to inspect bundle")
+}
@@ -0,0 +1,193 @@
+package junit
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Testsuites is the root element of a JUnit XML report.
+type Testsuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr,omitempty"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr,omitempty"`
+	Time     string      `xml:"time,attr,omitempty"`
+	Suites   []Testsuite `xml:"testsuite"`
+}
+
+// Testsuite is a group of test cases. Test suites can be nested.
+type Testsuite struct {
+	Name       string      `xml:"name,attr"`
+	Tests      int         `xml:"tests,attr"`
+	Failures   int         `xml:"failures,attr"`
+	Errors     int         `xml:"errors,attr"`
+	Skipped    int         `xml:"skipped,attr,omitempty"`
+	Time       string      `xml:"time,attr,omitempty"`
+	Timestamp  string      `xml:"timestamp,attr,omitempty"`
+	File       string      `xml:"file,attr,omitempty"`
+	Properties []Property  `xml:"properties>property,omitempty"`
+	Testcases  []Testcase  `xml:"testcase"`
+	Suites     []Testsuite `xml:"testsuite,omitempty"`
+	SystemOut  string      `xml:"system-out,omitempty"`
+	SystemErr  string      `xml:"system-err,omitempty"`
+}
+
+// Testcase is a single test result.
+type Testcase struct {
+	Name       string     `xml:"name,attr"`
+	Classname  string     `xml:"classname,attr,omitempty"`
+	File       string     `xml:"file,attr,omitempty"`
+	Time       string     `xml:"time,attr,omitempty"`
+	Properties []Property `xml:"properties>property,omitempty"`
+	Failure    *Result    `xml:"failure,omitempty"`
+	Error      *Result    `xml:"error,omitempty"`
+	Skipped    *Result    `xml:"skipped,omitempty"`
+	SystemOut  string     `xml:"system-out,omitempty"`
+	SystemErr  string     `xml:"system-err,omitempty"`
+}
+
+// Result is the failure, error or skipped result of a test case.
+type Result struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// Property is a key-value pair of a test suite or test case.
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// parse decodes a JUnit XML report into memory. Reports with a single
+// <testsuite> root element are wrapped into Testsuites. Bundles only use
+// Validate, which does not keep the report in memory.
+func parse(r io.Reader) (*Testsuites, error) {
+	d := xml.NewDecoder(r)
+
+	root, err := rootElement(d)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root.Name.Local {
+	case "testsuites":
+		var ts Testsuites
+		if err := d.DecodeElement(&ts, &root); err != nil {
+			return nil, fmt.Errorf("failed to decode testsuites: %w", err)
+		}
+		return &ts, nil
+	case "testsuite":
+		var s Testsuite
+		if err := d.DecodeElement(&s, &root); err != nil {
+			return nil, fmt.Errorf("failed to decode testsuite: %w", err)
+		}
+		return &Testsuites{
+			Tests:    s.Tests,
+			Failures: s.Failures,
+			Errors:   s.Errors,
+			Skipped:  s.Skipped,
+			Time:     s.Time,
+			Suites:   []Testsuite{s},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unexpected root element <%s>", root.Name.Local)
+	}
+}
+
+// Summary counts the elements of a JUnit XML report.
+type Summary struct {
+	Suites   int
+	Tests    int
+	Failures int
+	Errors   int
+	Skipped  int
+}
+
+// Validate checks that the report is well-formed JUnit XML without keeping
+// the report in memory. It returns a summary of the found test cases.
+func Validate(r io.Reader) (Summary, error) {
+	var sum Summary
+
+	d := xml.NewDecoder(r)
+
+	root, err := rootElement(d)
+	if err != nil {
+		return sum, err
+	}
+
+	switch root.Name.Local {
+	case "testsuites":
+	case "testsuite":
+		sum.Suites++
+	default:
+		return sum, fmt.Errorf("unexpected root element <%s>", root.Name.Local)
+	}
+
+	depth := 1
+	for depth > 0 {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return sum, fmt.Errorf("unexpected EOF: missing </%s>", root.Name.Local)
+		}
+		if err != nil {
+			return sum, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch t.Name.Local {
+			case "testsuite":
+				sum.Suites++
+			case "testcase":
+				sum.Tests++
+			case "failure":
+				sum.Failures++
+			case "error":
+				sum.Errors++
+			case "skipped":
+				sum.Skipped++
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	// Only whitespace, comments or processing instructions may follow the
+	// root element.
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return sum, fmt.Errorf("invalid XML: %w", err)
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			return sum, errors.New("unexpected element after root element")
+		}
+	}
+
+	return sum, nil
+}
+
+// rootElement returns the first start element of the document.
+func rootElement(d *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return xml.StartElement{}, errors.New("missing root element")
+		}
+		if err != nil {
+			return xml.StartElement{}, fmt.Errorf("invalid XML: %w", err)
+		}
+		if t, ok := tok.(xml.StartElement); ok {
+			return t, nil
+		}
+	}
+}
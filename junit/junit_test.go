@@ -0,0 +1,142 @@
+package junit
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		file    string
+		summary Summary
+		err     string
+	}{
+		{
+			file:    "../testdata/basic/reports/e2e-1.xml",
+			summary: Summary{Suites: 1, Tests: 2},
+		},
+		{
+			file:    "../testdata/github/reports/e2e-2.xml",
+			summary: Summary{Suites: 1, Tests: 2},
+		},
+		{
+			file:    "../testdata/invalid/reports/e2e-1.xml",
+			summary: Summary{Suites: 1, Tests: 2, Failures: 1, Skipped: 1},
+		},
+		{
+			file: "../testdata/invalid/reports/e2e-2.xml",
+			err:  "unexpected EOF",
+		},
+		{
+			file: "../testdata/invalid/reports/output.log",
+			err:  "missing root element",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert := assert.New(t)
+
+			f, err := os.Open(tt.file)
+			if !assert.NoError(err) {
+				return
+			}
+			defer f.Close()
+
+			summary, err := Validate(f)
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tt.summary, summary)
+		})
+	}
+}
+
+func TestValidateInvalid(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"empty", "", "missing root element"},
+		{"html", "<html><body></body></html>", "unexpected root element <html>"},
+		{"mismatched", "<testsuites><testsuite></testsuites>", "invalid XML"},
+		{"trailing", "<testsuite></testsuite><testsuite></testsuite>", "unexpected element after root element"},
+		{"json", `{"testResults": []}`, "missing root element"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate(strings.NewReader(tt.input))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := os.Open("../testdata/invalid/reports/e2e-1.xml")
+	if !assert.NoError(err) {
+		return
+	}
+	defer f.Close()
+
+	ts, err := parse(f)
+	if !assert.NoError(err) {
+		return
+	}
+
+	if !assert.Len(ts.Suites, 1) {
+		return
+	}
+	assert.Equal(2, ts.Tests)
+	assert.Equal(1, ts.Failures)
+
+	s := ts.Suites[0]
+	assert.Equal("first", s.Name)
+	assert.Equal("2025-01-09T20:45:22", s.Timestamp)
+	assert.Equal([]Property{{Name: "browser", Value: "chromium"}}, s.Properties)
+
+	if !assert.Len(s.Testcases, 2) {
+		return
+	}
+
+	tc := s.Testcases[0]
+	assert.Equal("e2e/first.spec.ts", tc.File)
+	assert.Equal("clicked button", tc.SystemOut)
+	if assert.NotNil(tc.Failure) {
+		assert.Equal("expected 1 to be 2", tc.Failure.Message)
+		assert.Equal("AssertionError", tc.Failure.Type)
+		assert.Equal("at e2e/first.spec.ts:12", tc.Failure.Body)
+	}
+	assert.Nil(tc.Skipped)
+
+	assert.NotNil(s.Testcases[1].Skipped)
+}
+
+func TestParseTestsuites(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := os.Open("../testdata/basic/reports/e2e-1.xml")
+	if !assert.NoError(err) {
+		return
+	}
+	defer f.Close()
+
+	ts, err := parse(f)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal("e2e tests", ts.Name)
+	assert.Equal(2, ts.Tests)
+	if assert.Len(ts.Suites, 1) {
+		assert.Len(ts.Suites[0].Testcases, 2)
+	}
+}
@@ -0,0 +1,94 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/testlabtools/record/tar"
+)
+
+// BundleManifestFileName is the name of the manifest added to each bundle.
+const BundleManifestFileName = "manifest.json"
+
+// BundleManifest lists the bundled reports, so they can be attributed to
+// their original paths and verified.
+type BundleManifest struct {
+	Files []BundleFile `json:"files"`
+}
+
+type BundleFile struct {
+	// Name is the short file name in the bundle, e.g. `reports/1.xml`.
+	Name string `json:"name"`
+
+	// Path is the original path of the report file. It is relative to the
+	// repo if the report is inside the repo.
+	Path string `json:"path"`
+
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Modified time.Time `json:"mtime"`
+
+	// Format is the detected file format, e.g. FormatJUnit.
+	Format string `json:"format"`
+}
+
+// newBundleManifest returns the manifest of the scanned reports and their
+// tar entries.
+func (c *Collector) newBundleManifest(reports []report, files []tar.File) BundleManifest {
+	var m BundleManifest
+	for i, r := range reports {
+		m.Files = append(m.Files, BundleFile{
+			Name:     files[i].Name,
+			Path:     c.relativePath(r.path),
+			Size:     r.size,
+			SHA256:   r.sha256,
+			Modified: r.modified,
+			Format:   r.format,
+		})
+	}
+	return m
+}
+
+// relativePath returns the slash separated path relative to the repo, or the
+// cleaned path if it is outside the repo.
+func (c *Collector) relativePath(path string) string {
+	clean := filepath.ToSlash(filepath.Clean(path))
+
+	repo, err := filepath.Abs(c.repo.Dir)
+	if err != nil {
+		return clean
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return clean
+	}
+
+	rel, err := filepath.Rel(repo, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return clean
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// manifestFile returns the tar entry of the manifest. Its content is created
+// when the entry is written, so it must be the last entry of the tarball to
+// contain the checksums of the written reports.
+func manifestFile(m func() BundleManifest) tar.File {
+	return tar.File{
+		Name: BundleManifestFileName,
+		Size: -1,
+		Open: func() (io.ReadCloser, error) {
+			var buf bytes.Buffer
+			if err := json.NewEncoder(&buf).Encode(m()); err != nil {
+				return nil, fmt.Errorf("failed to encode manifest: %w", err)
+			}
+			return io.NopCloser(&buf), nil
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package record
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+	"github.com/testlabtools/record/git"
+	"github.com/testlabtools/record/tar"
+)
+
+func TestBundleManifest(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	collector, err := NewCollector(l, "testdata/github/repo", srv.Env)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var data bytes.Buffer
+	err = collector.Bundle(BundleOptions{
+		Reports:        []string{"testdata/invalid/reports"},
+		InvalidReports: InvalidReportsKeep,
+	}, &data)
+	if !assert.NoError(err) {
+		return
+	}
+
+	info, err := InspectBundle(&data)
+	if !assert.NoError(err) || !assert.NotNil(info.Manifest) {
+		return
+	}
+
+	expected := []struct {
+		name   string
+		path   string
+		format string
+	}{
+		{"reports/1.xml", "testdata/invalid/reports/e2e-1.xml", FormatJUnit},
+		{"reports/2.xml", "testdata/invalid/reports/e2e-2.xml", FormatXML},
+		{"reports/3.log", "testdata/invalid/reports/output.log", FormatUnknown},
+	}
+
+	if !assert.Len(info.Manifest.Files, len(expected)) {
+		return
+	}
+
+	for i, e := range expected {
+		f := info.Manifest.Files[i]
+
+		content, err := os.ReadFile(e.path)
+		if !assert.NoError(err) {
+			return
+		}
+		sum := sha256.Sum256(content)
+
+		stat, err := os.Stat(e.path)
+		if !assert.NoError(err) {
+			return
+		}
+
+		assert.Equal(e.name, f.Name)
+		assert.Equal(e.path, f.Path)
+		assert.Equal(e.format, f.Format)
+		assert.Equal(int64(len(content)), f.Size)
+		assert.Equal(hex.EncodeToString(sum[:]), f.SHA256)
+		assert.True(stat.ModTime().Equal(f.Modified), "mtime of %s", e.path)
+	}
+}
+
+func TestBundleManifestRelativePath(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &Collector{repo: git.NewRepo("testdata")}
+
+	tests := map[string]string{
+		"testdata/github/reports/e2e-1.xml":   "github/reports/e2e-1.xml",
+		"./testdata/invalid/../basic/reports": "basic/reports",
+		"testdata":                            ".",
+		"collect.go":                          "collect.go",
+		"../record/reports.go":                "../record/reports.go",
+	}
+
+	for path, expected := range tests {
+		assert.Equal(expected, c.relativePath(path), path)
+	}
+}
+
+func TestBundleManifestChangedReport(t *testing.T) {
+	assert := assert.New(t)
+
+	content, err := os.ReadFile("testdata/basic/reports/e2e-1.xml")
+	if !assert.NoError(err) {
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "e2e-1.xml")
+	if !assert.NoError(os.WriteFile(path, content, 0o644)) {
+		return
+	}
+
+	r, _, err := scanReport(path)
+	if !assert.NoError(err) {
+		return
+	}
+
+	// The test runner appends to the report after it was scanned.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if !assert.NoError(err) {
+		return
+	}
+	f.WriteString("<!-- appended -->")
+	f.Close()
+
+	c := &Collector{repo: git.NewRepo("testdata")}
+	reports := []report{*r}
+	files := reportFiles(reports)
+	files = append(files, manifestFile(func() BundleManifest {
+		return c.newBundleManifest(reports, files[:1])
+	}))
+
+	var buf bytes.Buffer
+	if !assert.NoError(tar.Stream(files, &buf)) {
+		return
+	}
+
+	extracted, err := tar.Extract(&buf)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var m BundleManifest
+	if !assert.NoError(json.Unmarshal(extracted[BundleManifestFileName], &m)) || !assert.Len(m.Files, 1) {
+		return
+	}
+
+	bundled := extracted[m.Files[0].Name]
+	sum := sha256.Sum256(bundled)
+
+	assert.Equal(content, bundled)
+	assert.Equal(int64(len(bundled)), m.Files[0].Size)
+	assert.Equal(hex.EncodeToString(sum[:]), m.Files[0].SHA256)
+}
@@ -0,0 +1,283 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+)
+
+const (
+	// MinPartSize is the smallest part size accepted by S3, except for the
+	// last part.
+	MinPartSize = 5 << 20
+
+	// partConcurrency is the number of parts uploaded in parallel.
+	partConcurrency = 4
+
+	// MultipartStateExt is the file extension of the multipart upload state
+	// written next to a replayed bundle file.
+	MultipartStateExt = ".upload.json"
+)
+
+type multipartCreateRequest struct {
+	Size     int64 `json:"size"`
+	PartSize int64 `json:"partSize"`
+}
+
+type multipartCreateResponse struct {
+	Id       string `json:"id"`
+	UploadId string `json:"uploadId"`
+}
+
+type multipartPartsRequest struct {
+	PartNumbers []int `json:"partNumbers"`
+}
+
+type multipartPart struct {
+	PartNumber int    `json:"partNumber"`
+	Url        string `json:"url,omitempty"`
+	ETag       string `json:"etag,omitempty"`
+}
+
+type multipartPartsResponse struct {
+	Parts []multipartPart `json:"parts"`
+}
+
+type multipartCompleteRequest struct {
+	UploadId string          `json:"uploadId"`
+	Parts    []multipartPart `json:"parts"`
+}
+
+// multipartState is the progress of a multipart upload. It is persisted after
+// each uploaded part, so an interrupted upload can be resumed.
+type multipartState struct {
+	RunId    string `json:"runId"`
+	FileId   string `json:"fileId"`
+	UploadId string `json:"uploadId"`
+
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	PartSize int64  `json:"partSize"`
+
+	// Parts are the uploaded parts with their ETags.
+	Parts []multipartPart `json:"parts"`
+}
+
+// fileUpload is the data of a run file upload.
+type fileUpload struct {
+	data io.Reader
+	size int64
+
+	// open returns a new reader of the data for each attempt, if data is nil.
+	open func() (io.ReadCloser, error)
+
+	// partSize enables multipart uploads for data larger than partSize. If
+	// zero, the data is uploaded with a single request.
+	partSize int64
+
+	// stateFile is the path of the persisted multipart state. If empty, an
+	// interrupted multipart upload cannot be resumed.
+	stateFile string
+
+	// sha256 identifies the data in the multipart state.
+	sha256 string
+}
+
+func (f fileUpload) multipart() (io.ReaderAt, bool) {
+	ra, ok := f.data.(io.ReaderAt)
+	return ra, ok && f.partSize > 0 && f.size > f.partSize
+}
+
+func (f fileUpload) parts() int {
+	return int((f.size + f.partSize - 1) / f.partSize)
+}
+
+// matches returns true if the state belongs to the upload of the run.
+func (s *multipartState) matches(runId string, f fileUpload) bool {
+	return s.RunId == runId &&
+		s.Size == f.size &&
+		s.SHA256 == f.sha256 &&
+		s.PartSize == f.partSize
+}
+
+func readMultipartState(file string) (*multipartState, error) {
+	buf, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multipart state: %w", err)
+	}
+
+	var s multipartState
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode multipart state %q: %w", file, err)
+	}
+	return &s, nil
+}
+
+func writeMultipartState(file string, s *multipartState) error {
+	if file == "" {
+		return nil
+	}
+
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	// Replace the file atomically to keep the state intact on crashes.
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0o644); err != nil {
+		return fmt.Errorf("failed to write multipart state: %w", err)
+	}
+	return os.Rename(tmp, file)
+}
+
+// uploadMultipart uploads the data in parts to pre-signed part URLs and
+// returns the run file id. The parts are uploaded concurrently and each part
+// request is retried by the HTTP client.
+func (u *api) uploadMultipart(ctx context.Context, runId string, f fileUpload, data io.ReaderAt) (string, error) {
+	var state *multipartState
+
+	if f.stateFile != "" {
+		s, err := readMultipartState(f.stateFile)
+		if err != nil {
+			return "", err
+		}
+		if s != nil && s.matches(runId, f) {
+			u.log.Info("resume multipart upload", "fileId", s.FileId, "uploadedParts", len(s.Parts))
+			state = s
+		}
+	}
+
+	if state == nil {
+		var created multipartCreateResponse
+		path := fmt.Sprintf("api/v1/runs/%s/files/multipart", url.PathEscape(runId))
+		req := multipartCreateRequest{Size: f.size, PartSize: f.partSize}
+		if err := u.postJSON(ctx, path, http.StatusCreated, req, &created); err != nil {
+			return "", fmt.Errorf("failed to create multipart upload: %w", err)
+		}
+
+		state = &multipartState{
+			RunId:    runId,
+			FileId:   created.Id,
+			UploadId: created.UploadId,
+			Size:     f.size,
+			SHA256:   f.sha256,
+			PartSize: f.partSize,
+		}
+		if err := writeMultipartState(f.stateFile, state); err != nil {
+			return "", err
+		}
+	}
+
+	filePath := fmt.Sprintf("api/v1/runs/%s/files/%s/multipart", url.PathEscape(runId), url.PathEscape(state.FileId))
+
+	var missing []int
+	for n := 1; n <= f.parts(); n++ {
+		if !slices.ContainsFunc(state.Parts, func(p multipartPart) bool { return p.PartNumber == n }) {
+			missing = append(missing, n)
+		}
+	}
+
+	u.log.Debug("upload parts", "fileId", state.FileId, "parts", f.parts(), "missing", len(missing))
+
+	if len(missing) > 0 {
+		var urls multipartPartsResponse
+		if err := u.postJSON(ctx, filePath+"/parts", http.StatusOK, multipartPartsRequest{PartNumbers: missing}, &urls); err != nil {
+			return "", fmt.Errorf("failed to get part upload urls: %w", err)
+		}
+
+		if err := u.uploadParts(ctx, f, data, state, urls.Parts); err != nil {
+			return "", err
+		}
+	}
+
+	slices.SortFunc(state.Parts, func(a, b multipartPart) int { return a.PartNumber - b.PartNumber })
+
+	complete := multipartCompleteRequest{
+		UploadId: state.UploadId,
+		Parts:    state.Parts,
+	}
+	if err := u.postJSON(ctx, filePath+"/complete", http.StatusOK, complete, nil); err != nil {
+		return "", fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	removeMultipartState(u.log, f.stateFile)
+
+	return state.FileId, nil
+}
+
+// removeMultipartState removes the state file of a finished or abandoned
+// multipart upload.
+func removeMultipartState(l *slog.Logger, file string) {
+	if file == "" {
+		return
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		l.Warn("failed to remove multipart state", "file", file, "err", err)
+	}
+}
+
+// uploadParts uploads the parts concurrently and records each uploaded part
+// in the state. All parts are attempted, even if some of them fail, so a
+// resumed upload only sends the failed parts.
+func (u *api) uploadParts(ctx context.Context, f fileUpload, data io.ReaderAt, state *multipartState, parts []multipartPart) error {
+	var mu sync.Mutex
+	var errs []error
+
+	queue := make(chan multipartPart)
+	var wg sync.WaitGroup
+
+	for range min(partConcurrency, len(parts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for p := range queue {
+				offset := int64(p.PartNumber-1) * f.partSize
+				size := min(f.partSize, f.size-offset)
+
+				header, err := uploadFile(ctx, u.hc, p.Url, io.NewSectionReader(data, offset, size), size)
+
+				mu.Lock()
+				if err == nil {
+					u.log.Debug("uploaded part", "part", p.PartNumber, "size", size)
+					state.Parts = append(state.Parts, multipartPart{
+						PartNumber: p.PartNumber,
+						ETag:       header.Get("ETag"),
+					})
+					err = writeMultipartState(f.stateFile, state)
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to upload part %d: %w", p.PartNumber, err))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, p := range parts {
+		queue <- p
+	}
+	close(queue)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// multipartUnsupported returns true if the server has no multipart endpoints.
+func multipartUnsupported(err error) bool {
+	var status *statusError
+	return errors.As(err, &status) &&
+		(status.code == http.StatusNotFound || status.code == http.StatusMethodNotAllowed)
+}
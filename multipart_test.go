@@ -0,0 +1,237 @@
+package record
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestUploadMultipart(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	options := UploadOptions{
+		Reports:  []string{"testdata/github/reports"},
+		Repo:     "testdata/github/repo",
+		PartSize: 100,
+	}
+
+	err := Upload(l, srv.Env, options)
+	if !assert.NoError(err) {
+		return
+	}
+
+	if !assert.Len(srv.Files, 1) {
+		return
+	}
+
+	parts := (len(srv.Files[0]) + 99) / 100
+	assert.Greater(parts, 1)
+	assert.Equal(parts, srv.PartUploads)
+
+	files, err := srv.ExtractTar(0)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(files, "reports/1.xml")
+	assert.Contains(files, "reports/2.xml")
+	assert.Contains(files, BundleManifestFileName)
+}
+
+func TestUploadMultipartUnsupported(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusMethodNotAllowed} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			l := slogt.New(t)
+			assert := assert.New(t)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			srv.Handlers.CreateMultipart = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}
+
+			options := UploadOptions{
+				Reports:  []string{"testdata/github/reports"},
+				Repo:     "testdata/github/repo",
+				PartSize: 100,
+			}
+
+			err := Upload(l, srv.Env, options)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Len(srv.Files, 1)
+			assert.Zero(srv.PartUploads)
+		})
+	}
+}
+
+func TestUploadWithoutPartSize(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	srv.Handlers.CreateMultipart = func(w http.ResponseWriter, r *http.Request) {
+		assert.Fail("multipart upload created")
+	}
+
+	err := Upload(l, srv.Env, UploadOptions{
+		Reports: []string{"testdata/github/reports"},
+		Repo:    "testdata/github/repo",
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Len(srv.Files, 1)
+}
+
+func TestReplayMultipartResume(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.zst")
+	state := bundle + MultipartStateExt
+
+	err := Upload(l, srv.Env, UploadOptions{
+		Reports: []string{"testdata/github/reports"},
+		Repo:    "testdata/github/repo",
+		Output:  bundle,
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	saved, err := os.ReadFile(bundle)
+	if !assert.NoError(err) {
+		return
+	}
+
+	const partSize = 100
+	parts := (len(saved) + partSize - 1) / partSize
+	if !assert.Greater(parts, 2) {
+		return
+	}
+
+	hc := &http.Client{
+		Transport: &retryTransport{
+			maxRetries: 2,
+			log:        l,
+			sleep:      sleepNoop(t),
+		},
+	}
+
+	// Fail all attempts of the second part.
+	putPart := srv.Handlers.PutS3Part
+	srv.Handlers.PutS3Part = func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("partNumber") == "2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		putPart(w, r)
+	}
+
+	options := UploadOptions{
+		From:     bundle,
+		PartSize: partSize,
+		Client:   hc,
+	}
+
+	err = Upload(l, srv.Env, options)
+	assert.ErrorContains(err, "failed to upload part 2")
+	assert.Empty(srv.Files)
+	assert.Equal(parts-1, srv.PartUploads)
+
+	s, err := readMultipartState(state)
+	if !assert.NoError(err) || !assert.NotNil(s) {
+		return
+	}
+	assert.Len(s.Parts, parts-1)
+	for _, p := range s.Parts {
+		assert.NotEqual(2, p.PartNumber)
+		assert.NotEmpty(p.ETag)
+	}
+
+	// Resume the upload, which only sends the missing part.
+	srv.Handlers.PutS3Part = putPart
+
+	err = Upload(l, srv.Env, options)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(parts, srv.PartUploads)
+
+	if assert.Len(srv.Files, 1) {
+		assert.Equal(saved, srv.Files[0])
+	}
+
+	assert.NoFileExists(state)
+}
+
+func TestReplayMultipartUnsupported(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.zst")
+	state := bundle + MultipartStateExt
+
+	err := Upload(l, srv.Env, UploadOptions{
+		Reports: []string{"testdata/github/reports"},
+		Repo:    "testdata/github/repo",
+		Output:  bundle,
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	// The server only misses the endpoint to complete the upload.
+	srv.Handlers.CompleteMultipart = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+
+	err = Upload(l, srv.Env, UploadOptions{
+		From:     bundle,
+		PartSize: 100,
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Greater(srv.PartUploads, 1)
+	assert.Len(srv.Files, 1)
+	assert.NoFileExists(state)
+}
+
+func TestMultipartStateMatches(t *testing.T) {
+	assert := assert.New(t)
+
+	f := fileUpload{size: 300, partSize: 100, sha256: "abc"}
+	s := &multipartState{RunId: "1", Size: 300, PartSize: 100, SHA256: "abc"}
+
+	assert.True(s.matches("1", f))
+	assert.False(s.matches("2", f), "other run")
+
+	f.partSize = 50
+	assert.False(s.matches("1", f), "other part size")
+
+	assert.Equal(6, f.parts())
+}
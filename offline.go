@@ -0,0 +1,159 @@
+package record
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/testlabtools/record/client"
+)
+
+// RunManifest describes a bundle file saved for a later upload. It is
+// written next to the bundle file with the RunManifestExt extension.
+type RunManifest struct {
+	Run    client.CIRunRequest `json:"run"`
+	Saved  time.Time           `json:"saved"`
+	Size   int64               `json:"size"`
+	SHA256 string              `json:"sha256"`
+}
+
+const RunManifestExt = ".json"
+
+// saveBundle writes the bundle and its run manifest to o.Output.
+func saveBundle(l *slog.Logger, osEnv map[string]string, o UploadOptions) error {
+	collector, err := NewCollector(l, o.Repo, osEnv)
+	if err != nil {
+		return err
+	}
+
+	env := collector.Env()
+	l.Debug("collected env vars", "env", env)
+
+	// Store the start time, since `NOW()` of the API would be the time of
+	// the replay.
+	started := time.Now().UTC()
+	if o.Started != nil {
+		started = *o.Started
+	}
+
+	runReq := env.RunRequest()
+	runReq.Started = &started
+
+	f, err := os.Create(o.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle file: %w", err)
+	}
+	defer f.Close()
+
+	// The run is unknown offline, so assume it is the initial run and
+	// always add CODEOWNERS and the git summary.
+	h := sha256.New()
+	cw := &countWriter{w: io.MultiWriter(f, h)}
+	if err := collector.Bundle(o.bundleOptions(true), cw); err != nil {
+		return fmt.Errorf("failed to bundle: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle file: %w", err)
+	}
+
+	m := RunManifest{
+		Run:    runReq,
+		Saved:  time.Now().UTC(),
+		Size:   cw.n,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}
+
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(o.Output+RunManifestExt, buf, 0644); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+
+	l.Info("saved bundle", "file", o.Output, "size", m.Size, "sha256", m.SHA256)
+
+	return nil
+}
+
+// readRunManifest reads the run manifest of a bundle file.
+func readRunManifest(file string) (*RunManifest, error) {
+	buf, err := os.ReadFile(file + RunManifestExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run manifest: %w", err)
+	}
+
+	var m RunManifest
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode run manifest: %w", err)
+	}
+
+	return &m, nil
+}
+
+// replayBundle uploads a bundle file saved with saveBundle.
+// The multipart upload state is saved next to the bundle file, so an
+// interrupted upload is resumed when the bundle is replayed again.
+func replayBundle(ctx context.Context, l *slog.Logger, api *api, file string, partSize int64) error {
+	m, err := readRunManifest(file)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle file: %w", err)
+	}
+	defer f.Close()
+
+	// Verify the bundle before creating the run.
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle file: %w", err)
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if size != m.Size || sum != m.SHA256 {
+		return fmt.Errorf("bundle file %q does not match run manifest (size=%d sha256=%s)", file, size, sum)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind bundle file: %w", err)
+	}
+
+	l.Info("replay bundle", "file", file, "saved", m.Saved, "size", m.Size)
+
+	run, created, err := api.createRun(ctx, m.Run)
+	if err != nil {
+		return fmt.Errorf("failed to create run: %w", err)
+	}
+
+	l.Info("created run", "runId", run.Id, "created", created)
+
+	if m.Size == 0 {
+		l.Warn("saved tarball is empty. Skip file upload")
+		return nil
+	}
+
+	upload := fileUpload{
+		data:      f,
+		size:      m.Size,
+		partSize:  partSize,
+		stateFile: file + MultipartStateExt,
+		sha256:    m.SHA256,
+	}
+	if err := api.uploadRunFile(ctx, run, upload); err != nil {
+		return fmt.Errorf("failed to upload run: %w", err)
+	}
+
+	return nil
+}
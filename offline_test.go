@@ -0,0 +1,118 @@
+package record
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestUploadSaveAndReplay(t *testing.T) {
+	var tests = []struct {
+		name    string
+		options UploadOptions
+		files   int
+		corrupt bool
+		err     string
+	}{
+		{
+			name: "github",
+			options: UploadOptions{
+				Reports: []string{"testdata/github/reports"},
+				Repo:    "testdata/github/repo",
+			},
+			files: 1,
+		},
+		{
+			name: "empty reports",
+			options: UploadOptions{
+				Reports: []string{"testdata/unknown/reports"},
+			},
+		},
+		{
+			name: "corrupt bundle",
+			options: UploadOptions{
+				Reports: []string{"testdata/basic/reports"},
+			},
+			corrupt: true,
+			err:     "does not match run manifest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slogt.New(t)
+			assert := assert.New(t)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			bundle := filepath.Join(t.TempDir(), "bundle.tar.zst")
+
+			// Save bundle without API key.
+			env := make(map[string]string)
+			for key, val := range srv.Env {
+				env[key] = val
+			}
+			delete(env, "TESTLAB_KEY")
+
+			save := tt.options
+			save.Output = bundle
+			err := Upload(l, env, save)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Empty(srv.Runs)
+			assert.Empty(srv.Files)
+
+			saved, err := os.ReadFile(bundle)
+			if !assert.NoError(err) {
+				return
+			}
+
+			m, err := readRunManifest(bundle)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(int64(len(saved)), m.Size)
+			assert.NotNil(m.Run.Started)
+
+			if tt.corrupt {
+				err := os.WriteFile(bundle, append(saved, 0), 0644)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			err = Upload(l, srv.Env, UploadOptions{From: bundle})
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				assert.Empty(srv.Runs)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			run, ok := srv.Runs[srv.RunKey()]
+			if assert.True(ok) {
+				assert.Equal(*m.Run.Started, *run.Started)
+			}
+
+			if !assert.Len(srv.Files, tt.files) || tt.files == 0 {
+				return
+			}
+			assert.Equal(saved, srv.Files[0])
+
+			files, err := srv.ExtractTar(0)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Contains(files, "CODEOWNERS")
+			assert.Contains(files, GitSummaryFileName)
+		})
+	}
+}
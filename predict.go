@@ -1,15 +1,19 @@
 package record
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/testlabtools/record/client"
 	"github.com/testlabtools/record/runner"
+	"github.com/testlabtools/record/zstd"
 )
 
 type PredictOptions struct {
@@ -19,12 +23,78 @@ type PredictOptions struct {
 
 	Runner string
 
+	// ShardIndex and ShardTotal select the 1-based shard of the predicted
+	// tests. If ShardTotal is zero, all predicted tests are selected.
+	ShardIndex int
+	ShardTotal int
+
+	// Budget caps the estimated runtime of the predicted tests. If zero, the
+	// runtime is not limited. Predict fails if the predicted tests have no
+	// durations to estimate the runtime.
+	Budget time.Duration
+
+	// Confidence is the minimum confidence (0-1) of a predicted test. If
+	// zero, all predicted tests are selected.
+	Confidence float64
+
+	// Fallback is the test selection if the prediction fails. If empty,
+	// FallbackAll is used.
+	Fallback Fallback
+
+	// Explain writes a table that explains the selection of each test to
+	// Stderr.
+	Explain bool
+
+	// ExplainFile is the path of a JSON report that explains the selection
+	// of each test.
+	ExplainFile string
+
 	Debug bool
 
 	Stdin  io.Reader
 	Stdout io.Writer
+	Stderr io.Writer
 
 	client *http.Client
+
+	// selected is set to the number of selected tests, if not nil.
+	selected *int
+}
+
+// predictRequest adds the selection limits to the predict request.
+type predictRequest struct {
+	client.PredictRequest
+
+	// Budget is the estimated runtime budget of the selection in seconds.
+	Budget *float64 `json:"budget,omitempty"`
+
+	// Confidence is the minimum confidence of a selected test.
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// predictResponse is the predict response with the details of the tests.
+type predictResponse struct {
+	TestFiles []predictedTest `json:"testFiles"`
+}
+
+// predictedTest is a predicted test file. Duration is the historical duration
+// in seconds, Confidence is the probability (0-1) that the test is affected
+// by the changes and Reason and Score explain the prediction, if the API
+// returns them.
+type predictedTest struct {
+	Path       string   `json:"path"`
+	Duration   *float64 `json:"duration,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	Score      *float64 `json:"score,omitempty"`
+}
+
+func testPaths(tests []predictedTest) []string {
+	var paths []string
+	for _, t := range tests {
+		paths = append(paths, t.Path)
+	}
+	return paths
 }
 
 func Predict(l *slog.Logger, env map[string]string, o PredictOptions) error {
@@ -41,19 +111,93 @@ func Predict(l *slog.Logger, env map[string]string, o PredictOptions) error {
 		return fmt.Errorf("failed to parse stdin for format %q: %w", o.Runner, err)
 	}
 
+	if err := validateShard(o.ShardIndex, o.ShardTotal); err != nil {
+		return err
+	}
+
+	if o.Budget < 0 {
+		return fmt.Errorf("invalid budget %s: must not be negative", o.Budget)
+	}
+	if o.Confidence < 0 || o.Confidence > 1 {
+		return fmt.Errorf("invalid confidence %g: must be between 0 and 1", o.Confidence)
+	}
+
+	if err := validateFallback(o.Fallback); err != nil {
+		return err
+	}
+
+	strategy := StrategyAPI
+	ex := newExplainer(run.Files())
+
 	predicted, err := predict(l, env, o, run)
-	if err != nil {
+	if err == nil {
+		ex.exclude(predicted, reasonNotPredicted)
+
+		var dropped []droppedTest
+		predicted, dropped, err = limitTests(l, o, predicted)
+		if err != nil {
+			return err
+		}
+		ex.drop(dropped)
+	} else {
 		l.Error("failed to predict", "err", err)
 
-		// Fallback to original input.
-		l.Warn("fallback to original test input", "files", len(run.Files()))
-		predicted = run.Files()
+		predicted, strategy, err = fallbackTests(l, o, run, err)
+		if err != nil {
+			return err
+		}
+		ex.exclude(predicted, reasonNotRelated)
+	}
+
+	l.Info("selected tests", "strategy", strategy, "files", len(predicted))
+
+	if o.ShardTotal > 1 {
+		shard, duration := shardTests(predicted, o.ShardIndex, o.ShardTotal)
+		l.Info("shard predicted tests",
+			"index", o.ShardIndex,
+			"total", o.ShardTotal,
+			"files", len(shard),
+			"predicted", len(predicted),
+			"duration", duration,
+		)
+		ex.exclude(shard, fmt.Sprintf("not in shard %d/%d", o.ShardIndex, o.ShardTotal))
+		predicted = shard
+	}
+
+	if o.Explain || o.ExplainFile != "" {
+		changes, err := changedFiles(o)
+		if err != nil {
+			l.Warn("failed to get changed files for explain report", "err", err)
+		}
+
+		report := ex.report(strategy, changes)
+
+		if o.ExplainFile != "" {
+			if err := writeExplainFile(report, o.ExplainFile); err != nil {
+				return err
+			}
+		}
+
+		if o.Explain {
+			stderr := o.Stderr
+			if stderr == nil {
+				stderr = os.Stderr
+			}
+
+			if err := writeExplainTable(report, stderr); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.selected != nil {
+		*o.selected = len(predicted)
 	}
 
-	return run.Format(predicted, o.Stdout)
+	return run.Format(testPaths(predicted), o.Stdout)
 }
 
-func predict(l *slog.Logger, osEnv map[string]string, o PredictOptions, input runner.Parser) ([]string, error) {
+func predict(l *slog.Logger, osEnv map[string]string, o PredictOptions, input runner.Parser) ([]predictedTest, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
 
@@ -71,7 +215,7 @@ func predict(l *slog.Logger, osEnv map[string]string, o PredictOptions, input ru
 
 	if len(files) == 0 {
 		l.Warn("no test files read from stdin")
-		return files, nil
+		return nil, nil
 	}
 
 	collector, err := NewCollector(l, o.Repo, osEnv)
@@ -121,39 +265,133 @@ func predict(l *slog.Logger, osEnv map[string]string, o PredictOptions, input ru
 		})
 	}
 
-	req := client.PredictRequest{
-		CiRun: env.RunRequest(),
-		GitSummary: client.GitSummary{
-			DiffStat: ds,
+	req := predictRequest{
+		PredictRequest: client.PredictRequest{
+			CiRun: env.RunRequest(),
+			GitSummary: client.GitSummary{
+				DiffStat: ds,
+			},
+			TestFiles: testFiles,
 		},
-		TestFiles: testFiles,
 	}
-	predicted, err := api.predictTests(ctx, req)
+	if o.Budget > 0 {
+		budget := o.Budget.Seconds()
+		req.Budget = &budget
+	}
+	if o.Confidence > 0 {
+		req.Confidence = &o.Confidence
+	}
+	return api.predictTests(ctx, req)
+}
+
+// limitTests applies the budget and the confidence threshold to the predicted
+// tests and logs the dropped tests. The limits are sent to the API as well,
+// but they are applied again, since the API may return more tests.
+func limitTests(l *slog.Logger, o PredictOptions, predicted []predictedTest) ([]predictedTest, []droppedTest, error) {
+	if o.Budget == 0 && o.Confidence == 0 {
+		return predicted, nil, nil
+	}
+
+	selected, dropped, duration, estimated := selectTests(predicted, o.Budget, o.Confidence)
+
+	// The budget would silently select all tests.
+	if o.Budget > 0 && !estimated && len(selected) > 0 {
+		return nil, nil, fmt.Errorf("failed to apply budget %s: predicted tests have no durations", o.Budget)
+	}
+
+	for _, d := range dropped {
+		attrs := []any{"path", d.Path, "reason", d.Reason}
+		if d.Duration != nil {
+			attrs = append(attrs, "duration", *d.Duration)
+		}
+		if d.Confidence != nil {
+			attrs = append(attrs, "confidence", *d.Confidence)
+		}
+		l.Info("drop predicted test", attrs...)
+	}
+
+	l.Info("limit predicted tests",
+		"budget", o.Budget,
+		"confidence", o.Confidence,
+		"selected", len(selected),
+		"dropped", len(dropped),
+		"duration", duration,
+	)
+
+	return selected, dropped, nil
+}
+
+// predictTests predicts what tests to run for a CI run. The request is
+// compressed with zstd, since it can be several MB for large repos. If the
+// server rejects the compressed request as unsupported or bad, it is sent
+// again without compression, since servers and proxies without zstd support
+// answer with either status.
+func (u *api) predictTests(ctx context.Context, body predictRequest) ([]predictedTest, error) {
+	res, err := u.sendPredict(ctx, body, true)
 	if err != nil {
 		return nil, err
 	}
 
-	var out []string
-	for _, file := range predicted.TestFiles {
-		out = append(out, file.Path)
+	if res.StatusCode == http.StatusUnsupportedMediaType || res.StatusCode == http.StatusBadRequest {
+		res.Body.Close()
+		u.log.Warn("predict tests does not support zstd compression", "status", res.StatusCode)
+
+		res, err = u.sendPredict(ctx, body, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("predict tests returned invalid status code: %d", res.StatusCode)
 	}
 
-	return out, nil
+	var resp predictResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode predicted tests: %w", err)
+	}
+
+	return resp.TestFiles, nil
 }
 
-// predictTests predicts what tests to run for a CI run.
-func (u *api) predictTests(ctx context.Context, body client.PredictRequest) (*client.PredictResponse, error) {
-	params := &client.PredictTestsParams{
-		// TODO add zstd compression.
+// sendPredict sends the JSON request, compressed with zstd if compress is
+// true.
+func (u *api) sendPredict(ctx context.Context, body predictRequest, compress bool) (*http.Response, error) {
+	params := &client.PredictTestsParams{}
+
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+
+	var z io.WriteCloser
+	if compress {
+		var err error
+		z, err = zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		w = z
+
+		encoding := client.PredictTestsParamsContentEncodingZstd
+		params.ContentEncoding = &encoding
 	}
-	predict, err := u.api.PredictTestsWithResponse(ctx, params, body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to predict tests: %w", err)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return nil, fmt.Errorf("failed to encode predict request: %w", err)
+	}
+
+	if z != nil {
+		if err := z.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress predict request: %w", err)
+		}
 	}
 
-	if status := predict.StatusCode(); status != http.StatusOK {
-		return nil, fmt.Errorf("predict tests returned invalid status code: %d", status)
+	u.log.Debug("send predict request", "size", buf.Len(), "compressed", compress)
+
+	res, err := u.api.PredictTestsWithBody(ctx, params, "application/json", &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to predict tests: %w", err)
 	}
 
-	return predict.JSON200, nil
+	return res, nil
 }
@@ -38,7 +38,55 @@ func TestPredictFromGithub(t *testing.T) {
 				Runner: "go-test",
 			},
 			stdin:    "TestAB\nTestCD|TestEF\n",
-			expected: "^(TestCD|TestEF)$",
+			expected: "^(TestCD\\|TestEF)$",
+		},
+		{
+			name: "feature-json-go-test",
+			options: PredictOptions{
+				Repo:   "testdata/feature/repo",
+				Runner: "go-test",
+			},
+			stdin: `{"Action":"start","Package":"example.com/m/a"}
+{"Action":"output","Package":"example.com/m/a","Output":"TestZ\n"}
+{"Action":"output","Package":"example.com/m/a","Output":"TestA\n"}
+{"Action":"output","Package":"example.com/m/a","Output":"ok  \texample.com/m/a\t0.002s\n"}
+{"Action":"pass","Package":"example.com/m/a","Elapsed":0.002}
+{"Action":"output","Package":"example.com/m/a/b","Output":"TestA\n"}
+{"Action":"output","Package":"example.com/m/a/b","Output":"TestB\n"}
+{"Action":"output","Package":"example.com/m/a/b","Output":"ExampleB\n"}
+`,
+			expected: "-run '^(TestA)$' example.com/m/a\n" +
+				"-run '^(TestA|TestB|ExampleB)$' example.com/m/a/b\n",
+		},
+		{
+			name: "feature-json-go-subtests",
+			options: PredictOptions{
+				Repo:   "testdata/feature/repo",
+				Runner: "go-test",
+			},
+			stdin: `{"Action":"run","Package":"example.com/m/a","Test":"TestX"}
+{"Action":"run","Package":"example.com/m/a","Test":"TestA/case_1"}
+{"Action":"run","Package":"example.com/m/a","Test":"TestA/case[2]"}
+{"Action":"run","Package":"example.com/m/a","Test":"TestA/case[2]/deep"}
+{"Action":"run","Package":"example.com/m/a","Test":"TestB"}
+{"Action":"output","Package":"example.com/m/a","Test":"TestB","Output":"=== RUN   TestB\n"}
+`,
+			expected: "-run '^(TestB)$' example.com/m/a\n" +
+				"-run '^TestA$/^(case_1|case\\[2\\])$' example.com/m/a\n",
+		},
+		{
+			name: "feature-nextest",
+			options: PredictOptions{
+				Repo:   "testdata/feature/repo",
+				Runner: "nextest",
+			},
+			stdin: `{"rust-build-meta":{"target-directory":"/tmp/target","base-output-directories":["debug"]},"test-count":5,"rust-suites":{
+"rust::cli":{"package-name":"rust","binary-id":"rust::cli","binary-name":"cli","kind":"test","cwd":"testdata/symlink/app/rust","status":"listed","testcases":{"end_to_end":{"ignored":false,"filter-match":{"status":"matches"}}}},
+"rust":{"package-name":"rust","binary-id":"rust","binary-name":"rust","kind":"lib","cwd":"testdata/symlink/app/rust","status":"listed","testcases":{"tests::it_works":{"ignored":false,"filter-match":{"status":"matches"}},"parser::tests::parses":{"ignored":false,"filter-match":{"status":"matches"}},"parser::tests::fails":{"ignored":false,"filter-match":{"status":"matches"}}}},
+"rust::bin/tool":{"package-name":"rust","binary-id":"rust::bin/tool","binary-name":"tool","kind":"bin","cwd":"testdata/symlink/app/rust","status":"listed","testcases":{"tests::runs":{"ignored":false,"filter-match":{"status":"matches"}}}}
+}}
+`,
+			expected: "(binary_id(=rust) & (test(=tests::it_works))) | binary_id(=rust::bin/tool) | binary_id(=rust::cli)\n",
 		},
 	}
 	for _, tt := range tests {
@@ -181,3 +229,66 @@ func mustEncode(t *testing.T, w io.Writer, v interface{}) {
 		panic(err)
 	}
 }
+
+func TestPredictCompression(t *testing.T) {
+	var tests = []struct {
+		name      string
+		status    int
+		encodings []string
+	}{
+		{
+			name:      "zstd",
+			encodings: []string{"zstd"},
+		},
+		{
+			name:      "unsupported-zstd",
+			status:    http.StatusUnsupportedMediaType,
+			encodings: []string{"zstd", ""},
+		},
+		{
+			name:      "bad-request-zstd",
+			status:    http.StatusBadRequest,
+			encodings: []string{"zstd", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slogt.New(t)
+			assert := assert.New(t)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			predict := srv.Handlers.Predict
+			srv.Handlers.Predict = func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 && r.Header.Get("Content-Encoding") != "" {
+					w.WriteHeader(tt.status)
+					return
+				}
+				predict(w, r)
+			}
+
+			var out bytes.Buffer
+			opt := PredictOptions{
+				Repo:   "testdata/feature/repo",
+				Runner: "go-test",
+				Stdin:  strings.NewReader("TestA\nTestB\n"),
+				Stdout: &out,
+			}
+
+			err := Predict(l, srv.Env, opt)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal("^(TestA|TestB)$", out.String())
+			assert.Equal(tt.encodings, srv.PredictEncodings)
+
+			if assert.Len(srv.Predicts, 1) {
+				assert.Len(srv.Predicts[0].TestFiles, 2)
+				assert.NotEmpty(srv.Predicts[0].GitSummary.DiffStat)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package record
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/testlabtools/record/client"
+)
+
+// CIProvider maps the environment of a CI system to a RunEnv.
+type CIProvider interface {
+	// Name returns the provider name sent to the API. It must be one of the
+	// names known to the API, see RegisterCIProvider.
+	Name() client.CIProviderName
+
+	// Detect returns true if the env vars belong to this CI provider.
+	Detect(env map[string]string) bool
+
+	// RunEnv builds the run env from the env vars. The Group and CIEnv
+	// fields are set by the collector.
+	RunEnv(env map[string]string) (RunEnv, error)
+
+	// ExtraEnv lists the env var names that are added to RunEnv.CIEnv when
+	// they are set.
+	ExtraEnv() []string
+}
+
+var (
+	ciProvidersMu sync.Mutex
+	ciProviders   = []CIProvider{
+		githubProvider{},
+		gitlabProvider{},
+	}
+)
+
+// RegisterCIProvider adds a CI provider to the registry. Registered
+// providers are detected before the built-in ones, so a provider can
+// override the detection of a built-in provider.
+//
+// The API only accepts the provider names of client.CIProviderName, which are
+// github and gitlab. A registered provider therefore has to report one of
+// them as its Name, e.g. to adapt an in-house CI system that is compatible
+// with the env vars of GitLab CI.
+func RegisterCIProvider(p CIProvider) {
+	ciProvidersMu.Lock()
+	defer ciProvidersMu.Unlock()
+
+	ciProviders = append([]CIProvider{p}, ciProviders...)
+}
+
+// detectCIProvider returns the first provider that detects the env vars, or
+// nil if no provider matches.
+func detectCIProvider(env map[string]string) CIProvider {
+	ciProvidersMu.Lock()
+	defer ciProvidersMu.Unlock()
+
+	for _, p := range ciProviders {
+		if p.Detect(env) {
+			return p
+		}
+	}
+	return nil
+}
+
+func parseInts(env map[string]string, numeric map[string]*int) error {
+	for key, ref := range numeric {
+		val, err := strconv.Atoi(env[key])
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %s", key, err)
+		}
+		*ref = val
+	}
+
+	return nil
+}
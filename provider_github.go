@@ -0,0 +1,46 @@
+package record
+
+import "github.com/testlabtools/record/client"
+
+// githubProvider maps the default env vars of GitHub Actions.
+//
+// See https://docs.github.com/en/actions/reference/variables-reference
+type githubProvider struct{}
+
+func (githubProvider) Name() client.CIProviderName {
+	return client.Github
+}
+
+func (githubProvider) Detect(env map[string]string) bool {
+	return env["GITHUB_ACTIONS"] != ""
+}
+
+func (githubProvider) ExtraEnv() []string {
+	return []string{
+		"GITHUB_BASE_REF",
+		"GITHUB_HEAD_REF",
+		"GITHUB_JOB",
+		"GITHUB_REF_TYPE",
+	}
+}
+
+func (p githubProvider) RunEnv(env map[string]string) (RunEnv, error) {
+	re := RunEnv{
+		ActorName:      env["GITHUB_ACTOR"],
+		CIProviderName: p.Name(),
+		GitRef:         env["GITHUB_REF"],
+		GitRefName:     env["GITHUB_REF_NAME"],
+		GitRepo:        env["GITHUB_REPOSITORY"],
+		GitSha:         env["GITHUB_SHA"],
+	}
+
+	numeric := map[string]*int{
+		"GITHUB_RUN_ATTEMPT": &re.RunAttempt,
+		"GITHUB_RUN_ID":      &re.RunId,
+		"GITHUB_RUN_NUMBER":  &re.RunNumber,
+	}
+
+	err := parseInts(env, numeric)
+
+	return re, err
+}
@@ -0,0 +1,48 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+)
+
+func TestGithubProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	env := map[string]string{
+		"GITHUB_ACTIONS":     "true",
+		"GITHUB_ACTOR":       "smvv",
+		"GITHUB_REF":         "refs/heads/feature-branch-1",
+		"GITHUB_REF_NAME":    "feature-branch-1",
+		"GITHUB_REPOSITORY":  "octocat/Hello-World",
+		"GITHUB_RUN_ATTEMPT": "2",
+		"GITHUB_RUN_ID":      "1658821493",
+		"GITHUB_RUN_NUMBER":  "3",
+		"GITHUB_SHA":         "ffac537e6cbbf934b08745a378932722df287a53",
+	}
+
+	p := githubProvider{}
+	if !assert.True(p.Detect(env)) {
+		return
+	}
+	assert.False(p.Detect(gitlabEnv()))
+
+	re, err := p.RunEnv(env)
+	if !assert.NoError(err) {
+		return
+	}
+
+	expected := RunEnv{
+		ActorName:      "smvv",
+		CIProviderName: client.Github,
+		GitRef:         "refs/heads/feature-branch-1",
+		GitRefName:     "feature-branch-1",
+		GitRepo:        "octocat/Hello-World",
+		GitSha:         "ffac537e6cbbf934b08745a378932722df287a53",
+		RunAttempt:     2,
+		RunId:          1658821493,
+		RunNumber:      3,
+	}
+	assert.Equal(expected, re)
+}
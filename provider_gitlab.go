@@ -0,0 +1,64 @@
+package record
+
+import "github.com/testlabtools/record/client"
+
+// gitlabProvider maps the predefined env vars of GitLab CI/CD.
+//
+// See https://docs.gitlab.com/ci/variables/predefined_variables/
+type gitlabProvider struct{}
+
+func (gitlabProvider) Name() client.CIProviderName {
+	return client.Gitlab
+}
+
+func (gitlabProvider) Detect(env map[string]string) bool {
+	return env["GITLAB_CI"] != ""
+}
+
+func (gitlabProvider) ExtraEnv() []string {
+	return []string{
+		"CI_DEFAULT_BRANCH",
+		"CI_JOB_ID",
+		"CI_JOB_NAME",
+		"CI_JOB_STAGE",
+		"CI_MERGE_REQUEST_IID",
+		"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME",
+		"CI_MERGE_REQUEST_TARGET_BRANCH_NAME",
+		"CI_PIPELINE_SOURCE",
+	}
+}
+
+func (p gitlabProvider) RunEnv(env map[string]string) (RunEnv, error) {
+	re := RunEnv{
+		ActorName:      env["GITLAB_USER_LOGIN"],
+		CIProviderName: p.Name(),
+		GitRef:         gitlabRef(env),
+		GitRefName:     env["CI_COMMIT_REF_NAME"],
+		GitRepo:        env["CI_PROJECT_PATH"],
+		GitSha:         env["CI_COMMIT_SHA"],
+		// GitLab has no run attempts: a retried job gets a new job id
+		// within the same pipeline.
+		RunAttempt: 1,
+	}
+
+	numeric := map[string]*int{
+		"CI_PIPELINE_ID":  &re.RunId,
+		"CI_PIPELINE_IID": &re.RunNumber,
+	}
+
+	err := parseInts(env, numeric)
+
+	return re, err
+}
+
+// gitlabRef returns the fully qualified git ref of a GitLab pipeline, since
+// GitLab only exposes the short ref name.
+func gitlabRef(env map[string]string) string {
+	if tag := env["CI_COMMIT_TAG"]; tag != "" {
+		return "refs/tags/" + tag
+	}
+	if iid := env["CI_MERGE_REQUEST_IID"]; iid != "" {
+		return "refs/merge-requests/" + iid + "/head"
+	}
+	return "refs/heads/" + env["CI_COMMIT_REF_NAME"]
+}
@@ -0,0 +1,94 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+)
+
+func gitlabEnv() map[string]string {
+	return map[string]string{
+		"GITLAB_CI":            "true",
+		"GITLAB_USER_LOGIN":    "smvv",
+		"CI_COMMIT_REF_NAME":   "feature-branch-1",
+		"CI_COMMIT_SHA":        "ffac537e6cbbf934b08745a378932722df287a53",
+		"CI_MERGE_REQUEST_IID": "42",
+		"CI_PIPELINE_ID":       "1538227016",
+		"CI_PIPELINE_IID":      "3",
+		"CI_PROJECT_PATH":      "octocat/Hello-World",
+	}
+}
+
+func TestGitlabProvider(t *testing.T) {
+	var tests = []struct {
+		name string
+		env  map[string]string
+		ref  string
+		err  string
+	}{
+		{
+			name: "merge-request",
+			ref:  "refs/merge-requests/42/head",
+		},
+		{
+			name: "branch",
+			env: map[string]string{
+				"CI_MERGE_REQUEST_IID": "",
+			},
+			ref: "refs/heads/feature-branch-1",
+		},
+		{
+			name: "tag",
+			env: map[string]string{
+				"CI_COMMIT_REF_NAME":   "1.0.2",
+				"CI_COMMIT_TAG":        "1.0.2",
+				"CI_MERGE_REQUEST_IID": "",
+			},
+			ref: "refs/tags/1.0.2",
+		},
+		{
+			name: "invalid-pipeline-id",
+			env: map[string]string{
+				"CI_PIPELINE_ID": "",
+			},
+			err: `failed to parse "CI_PIPELINE_ID"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			env := gitlabEnv()
+			for key, val := range tt.env {
+				env[key] = val
+			}
+
+			p := gitlabProvider{}
+			if !assert.True(p.Detect(env)) {
+				return
+			}
+
+			re, err := p.RunEnv(env)
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			expected := RunEnv{
+				ActorName:      "smvv",
+				CIProviderName: client.Gitlab,
+				GitRef:         tt.ref,
+				GitRefName:     env["CI_COMMIT_REF_NAME"],
+				GitRepo:        "octocat/Hello-World",
+				GitSha:         "ffac537e6cbbf934b08745a378932722df287a53",
+				RunAttempt:     1,
+				RunId:          1538227016,
+				RunNumber:      3,
+			}
+			assert.Equal(expected, re)
+		})
+	}
+}
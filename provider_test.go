@@ -0,0 +1,93 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+)
+
+// customProvider is an in-house CI system that also sets GITLAB_CI. It
+// reports the gitlab name, since the API does not know other providers.
+type customProvider struct{}
+
+func (customProvider) Name() client.CIProviderName {
+	return client.Gitlab
+}
+
+func (customProvider) Detect(env map[string]string) bool {
+	return env["CUSTOM_CI"] != ""
+}
+
+func (customProvider) ExtraEnv() []string {
+	return []string{"CUSTOM_CI_WORKER"}
+}
+
+func (p customProvider) RunEnv(env map[string]string) (RunEnv, error) {
+	return RunEnv{
+		ActorName:      "custom",
+		CIProviderName: p.Name(),
+		RunAttempt:     1,
+		RunId:          7,
+		RunNumber:      7,
+	}, nil
+}
+
+func TestDetectCIProvider(t *testing.T) {
+	var tests = []struct {
+		name     string
+		env      map[string]string
+		provider CIProvider
+	}{
+		{
+			name:     "github",
+			env:      map[string]string{"GITHUB_ACTIONS": "true"},
+			provider: githubProvider{},
+		},
+		{
+			name:     "gitlab",
+			env:      map[string]string{"GITLAB_CI": "true"},
+			provider: gitlabProvider{},
+		},
+		{
+			name: "unknown",
+			env:  map[string]string{"CI": "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.provider, detectCIProvider(tt.env))
+		})
+	}
+}
+
+func TestRegisterCIProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	saved := ciProviders
+	defer func() { ciProviders = saved }()
+
+	RegisterCIProvider(customProvider{})
+
+	env := gitlabEnv()
+	env["TESTLAB_GROUP"] = "e2e"
+	env["CUSTOM_CI"] = "true"
+	env["CUSTOM_CI_WORKER"] = "worker-1"
+
+	// Registered providers take precedence over built-in ones.
+	assert.Equal(customProvider{}, detectCIProvider(env))
+
+	c, err := NewCollector(l, "testdata/unknown/repo", env)
+	if !assert.NoError(err) {
+		return
+	}
+
+	re := c.Env()
+	assert.Equal("custom", re.ActorName)
+	assert.Equal("e2e", re.Group)
+	assert.Equal("worker-1", (*re.CIEnv)["CUSTOM_CI_WORKER"])
+	assert.NotContains(*re.CIEnv, "CI_MERGE_REQUEST_IID")
+}
@@ -0,0 +1,325 @@
+package record
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/testlabtools/record/glob"
+	"github.com/testlabtools/record/junit"
+	"github.com/testlabtools/record/tar"
+)
+
+// findReports returns the report files of the bundle options in order. Each
+// report path is a file, a directory (walked recursively) or a glob pattern.
+// Files are deduplicated and filtered by the include and exclude patterns.
+func (c *Collector) findReports(o BundleOptions, limit int) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range append(slices.Clip(o.Reports), o.ReportsDir) {
+		if pattern == "" {
+			continue
+		}
+
+		matches, err := expandReports(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		root := reportsRoot(pattern)
+
+		found := 0
+		for _, file := range matches {
+			ok, err := filterReport(file, root, o.Include, o.Exclude)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			found++
+
+			key := filepath.Clean(file)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			files = append(files, file)
+
+			if len(files) > limit {
+				// Avoid bundling a whole repo.
+				return nil, fmt.Errorf("too many files (%d > %d) found", len(files), limit)
+			}
+		}
+
+		if found == 0 {
+			c.log.Warn("report path matched no files", "pattern", pattern)
+		} else {
+			c.log.Debug("report path matched files", "pattern", pattern, "files", found)
+		}
+	}
+
+	return files, nil
+}
+
+// expandReports returns the files of a report file, directory or glob
+// pattern. Missing paths return no files.
+func expandReports(pattern string) ([]string, error) {
+	if glob.HasMeta(pattern) {
+		return glob.Glob(pattern)
+	}
+
+	if fileExists(pattern) {
+		return []string{pattern}, nil
+	}
+
+	if !dirExists(pattern) {
+		return nil, nil
+	}
+
+	var files []string
+
+	err := filepath.WalkDir(pattern, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+
+		if d.IsDir() {
+			// Skip directories
+			return nil
+		}
+
+		files = append(files, path)
+		return nil
+	})
+
+	return files, err
+}
+
+// reportsRoot returns the directory of a report path, which include and
+// exclude patterns with a slash are relative to.
+func reportsRoot(pattern string) string {
+	if glob.HasMeta(pattern) {
+		return glob.Base(pattern)
+	}
+	if dirExists(pattern) {
+		return pattern
+	}
+	return filepath.Dir(pattern)
+}
+
+// filterReport returns true if the file matches any include pattern (or
+// there are none) and no exclude pattern.
+func filterReport(file, root string, include, exclude []string) (bool, error) {
+	for _, pattern := range exclude {
+		ok, err := matchReport(pattern, file, root)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+
+	if len(include) == 0 {
+		return true, nil
+	}
+
+	for _, pattern := range include {
+		ok, err := matchReport(pattern, file, root)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// matchReport matches patterns without a slash against the file name, e.g.
+// `*.log`, and other patterns against the path relative to the report root,
+// e.g. `e2e/**/*.xml`.
+func matchReport(pattern, file, root string) (bool, error) {
+	if !strings.Contains(filepath.ToSlash(pattern), "/") {
+		return glob.Match(pattern, filepath.Base(file))
+	}
+
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		rel = file
+	}
+	return glob.Match(pattern, filepath.ToSlash(rel))
+}
+
+// InvalidReports decides how XML report files that are not valid JUnit XML
+// are handled when bundling. Other files, e.g. logs or screenshots, are
+// bundled without validation.
+type InvalidReports string
+
+const (
+	// InvalidReportsOmit logs and omits invalid reports. This is the default.
+	InvalidReportsOmit InvalidReports = "omit"
+
+	// InvalidReportsFail fails the bundle on the first invalid report.
+	InvalidReportsFail InvalidReports = "fail"
+
+	// InvalidReportsKeep logs and bundles invalid reports.
+	InvalidReportsKeep InvalidReports = "keep"
+)
+
+// report is a scanned report file.
+type report struct {
+	path     string
+	size     int64
+	modified time.Time
+	sha256   string
+	format   string
+}
+
+// Report formats detected while scanning.
+const (
+	FormatJUnit   = "junit"
+	FormatXML     = "xml"
+	FormatJSON    = "json"
+	FormatUnknown = "unknown"
+)
+
+// scanReports validates each XML report file. Invalid reports are handled
+// according to the policy.
+func (c *Collector) scanReports(paths []string, policy InvalidReports) ([]report, error) {
+	switch policy {
+	case "", InvalidReportsOmit, InvalidReportsFail, InvalidReportsKeep:
+	default:
+		return nil, fmt.Errorf("unknown invalid reports policy: %q", policy)
+	}
+
+	var reports []report
+
+	for _, path := range paths {
+		r, summary, err := scanReport(path)
+		if r == nil {
+			return nil, err
+		}
+
+		if err != nil {
+			switch policy {
+			case InvalidReportsFail:
+				return nil, fmt.Errorf("invalid report %q: %w", path, err)
+			case InvalidReportsKeep:
+				c.log.Warn("keep invalid report", "file", path, "format", r.format, "err", err)
+				reports = append(reports, *r)
+			default:
+				c.log.Warn("omit invalid report", "file", path, "err", err)
+			}
+			continue
+		}
+
+		if r.format == FormatJUnit {
+			c.log.Debug("validated report", "file", path,
+				"suites", summary.Suites,
+				"tests", summary.Tests,
+				"failures", summary.Failures,
+				"errors", summary.Errors,
+				"skipped", summary.Skipped,
+			)
+		}
+		reports = append(reports, *r)
+	}
+
+	return reports, nil
+}
+
+// scanReport returns the report info and the validation result of a file.
+// Only files with the .xml extension are validated. If the file cannot be
+// read, the report is nil.
+func scanReport(path string) (*report, junit.Summary, error) {
+	var summary junit.Summary
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, summary, fmt.Errorf("failed to open file %q: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, summary, fmt.Errorf("failed to stat file %q: %w", path, err)
+	}
+
+	r := &report{
+		path:     path,
+		size:     info.Size(),
+		modified: info.ModTime().UTC(),
+		format:   detectFormat(path),
+	}
+
+	if r.format != FormatXML {
+		return r, summary, nil
+	}
+
+	summary, invalid := junit.Validate(f)
+	if invalid == nil {
+		r.format = FormatJUnit
+	}
+
+	return r, summary, invalid
+}
+
+// detectFormat guesses the format of a file by its extension.
+func detectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return FormatXML
+	case ".json":
+		return FormatJSON
+	default:
+		return FormatUnknown
+	}
+}
+
+// reportFiles returns the tar entries of the reports using short file names.
+// The contents are read when the tarball is written. The checksum of each
+// report is computed from the bytes written into the tarball, so it matches
+// the bundle even if the file changed after it was scanned.
+func reportFiles(reports []report) []tar.File {
+	var files []tar.File
+
+	for i, r := range reports {
+		files = append(files, tar.File{
+			Name: fmt.Sprintf("reports/%d%s", i+1, filepath.Ext(r.path)),
+			Size: r.size,
+			Open: func() (io.ReadCloser, error) {
+				f, err := os.Open(r.path)
+				if err != nil {
+					return nil, err
+				}
+				return &hashedFile{file: f, hash: sha256.New(), sum: &reports[i].sha256}, nil
+			},
+		})
+	}
+
+	return files
+}
+
+// hashedFile hashes the read content of the file. The hex encoded checksum is
+// stored in sum when the file is closed.
+type hashedFile struct {
+	file *os.File
+	hash hash.Hash
+	sum  *string
+}
+
+func (h *hashedFile) Read(p []byte) (int, error) {
+	n, err := h.file.Read(p)
+	h.hash.Write(p[:n])
+	return n, err
+}
+
+func (h *hashedFile) Close() error {
+	*h.sum = hex.EncodeToString(h.hash.Sum(nil))
+	return h.file.Close()
+}
@@ -1,11 +1,13 @@
 package record
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"math/rand/v2"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -17,7 +19,10 @@ type retryTransport struct {
 	base       http.RoundTripper
 	maxRetries int
 
+	// sleep replaces the wait between attempts in tests. If nil, the
+	// transport waits until the backoff passed or the request is canceled.
 	sleep func(d time.Duration)
+	now   func() time.Time
 
 	log *slog.Logger
 
@@ -56,8 +61,8 @@ func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 			}
 		}
 
-		if t.sleep == nil {
-			t.sleep = time.Sleep
+		if t.now == nil {
+			t.now = time.Now
 		}
 
 		if t.log == nil {
@@ -70,10 +75,32 @@ func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	var resp *http.Response
 	var err error
 
-	retries := t.maxRetries
+	// Send the request at least once.
+	retries := max(t.maxRetries, 1)
+
+	// Requests with a body can only be retried if the body can be rebuilt,
+	// since the base transport consumes it.
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
 
 	for i := 0; i < retries; i++ {
-		resp, err = t.base.RoundTrip(req)
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+
+		r := req
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to replay request body: %w", err)
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		resp, err = t.base.RoundTrip(r)
+
+		var wait time.Duration
+
 		if err != nil {
 			// Could be a network error, DNS issue, etc.—retry
 			t.log.Info("request failed with error",
@@ -82,30 +109,125 @@ func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 				"attempt", i+1,
 				"err", err,
 			)
-		} else if resp.StatusCode < 500 {
-			// Return on success or any non-5xx code
+		} else if !retryableStatus(resp.StatusCode) {
+			// Return on success or any other non-retryable code
 			return resp, nil
 		} else {
-			// If 5xx, we want to retry. Close response body to avoid leaks.
-			resp.Body.Close()
-			t.log.Info("request failed with server error",
+			wait = retryAfter(resp.Header, t.now())
+
+			t.log.Info("request failed with retryable status",
 				"method", req.Method,
 				"path", req.URL.Path,
 				"attempt", i+1,
 				"status", resp.StatusCode,
+				"retryAfter", wait,
 			)
 		}
 
-		// Apply exponential backoff with jitter
+		if i == retries-1 {
+			break
+		}
+
+		if !replayable {
+			t.log.Warn("cannot retry request since its body cannot be replayed",
+				"method", req.Method,
+				"path", req.URL.Path,
+				"attempt", i+1,
+			)
+			return lastResult(resp, err)
+		}
+
+		// Apply exponential backoff with jitter, but wait at least as long as
+		// the server asked for.
 		jitter := time.Duration(t.randInt64(int64(backoff / 2)))
-		sleepDuration := backoff + jitter
-		t.sleep(sleepDuration)
+		sleepDuration := max(backoff+jitter, wait)
 		backoff *= 2
+
+		// Stop retrying if the next attempt would exceed the deadline.
+		if deadline, ok := req.Context().Deadline(); ok && t.now().Add(sleepDuration).After(deadline) {
+			t.log.Info("stop retrying since the request deadline is reached",
+				"method", req.Method,
+				"path", req.URL.Path,
+				"attempt", i+1,
+				"sleep", sleepDuration,
+			)
+			return lastResult(resp, err)
+		}
+
+		if resp != nil {
+			// Close response body to avoid leaks.
+			resp.Body.Close()
+		}
+
+		if err := t.wait(req.Context(), sleepDuration); err != nil {
+			return nil, err
+		}
 	}
 
 	// If we exhausted all retries, return the last error (or a custom one)
 	if err == nil {
-		return nil, fmt.Errorf("all retry attempts failed with 5xx status codes")
+		resp.Body.Close()
+		return nil, fmt.Errorf("all retry attempts failed with status code %d", resp.StatusCode)
 	}
 	return nil, err
 }
+
+// wait sleeps for d or until the context is done.
+func (t *retryTransport) wait(ctx context.Context, d time.Duration) error {
+	if t.sleep != nil {
+		t.sleep(d)
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lastResult returns the result of the last attempt, which is either an
+// error or a response with a retryable status code.
+func lastResult(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// retryableStatus returns true for server errors, timeouts and rate limits.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	default:
+		return code >= 500
+	}
+}
+
+// retryAfter returns the wait duration of the Retry-After header, which is
+// either in seconds or an HTTP date. It returns zero if the header is missing
+// or invalid.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	if date, err := http.ParseTime(v); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
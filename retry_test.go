@@ -2,9 +2,12 @@ package record
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,11 +26,21 @@ type mockRoundTripper struct {
 	responses []*http.Response
 	errors    []error
 	callCount int
+
+	// bodies are the request bodies of each call.
+	bodies [][]byte
 }
 
 func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	defer func() { m.callCount++ }()
 
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	m.bodies = append(m.bodies, body)
+
 	errs := m.errors
 	if errs == nil {
 		errs = make([]error, len(m.responses))
@@ -191,3 +204,433 @@ func TestTransportError(t *testing.T) {
 	assert.NotNil(resp, "response should not be nil")
 	assert.Equal(2, mock.callCount, "should only make two attempts (first was error, second is success)")
 }
+
+// sleepRecorder records the sleep durations instead of sleeping.
+type sleepRecorder struct {
+	durations []time.Duration
+}
+
+func (s *sleepRecorder) sleep(d time.Duration) {
+	s.durations = append(s.durations, d)
+}
+
+func newRetryAfterResponse(statusCode int, retryAfter string) *http.Response {
+	resp := newHTTPResponse(statusCode)
+	resp.Header = http.Header{
+		"Retry-After": []string{retryAfter},
+	}
+	return resp
+}
+
+func TestRetryOnRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	mock := &mockRoundTripper{
+		responses: []*http.Response{
+			newHTTPResponse(429),
+			newHTTPResponse(408),
+			newHTTPResponse(200),
+		},
+	}
+
+	rt := &retryTransport{
+		base:       mock,
+		maxRetries: 5,
+		log:        l,
+		sleep:      sleepNoop(t),
+	}
+
+	client := &http.Client{Transport: rt}
+	req, _ := http.NewRequest("GET", "http://test", nil)
+
+	resp, err := client.Do(req)
+	assert.NoError(err)
+	assert.Equal(3, mock.callCount)
+	assert.Equal(200, resp.StatusCode)
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	mock := &mockRoundTripper{
+		responses: []*http.Response{
+			newHTTPResponse(404),
+			newHTTPResponse(200),
+		},
+	}
+
+	rt := &retryTransport{
+		base:       mock,
+		maxRetries: 5,
+		log:        l,
+		sleep:      sleepNoop(t),
+	}
+
+	client := &http.Client{Transport: rt}
+	req, _ := http.NewRequest("GET", "http://test", nil)
+
+	resp, err := client.Do(req)
+	assert.NoError(err)
+	assert.Equal(1, mock.callCount)
+	assert.Equal(404, resp.StatusCode)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		retryAfter string
+		expected   time.Duration
+	}{
+		{
+			name:       "seconds",
+			retryAfter: "7",
+			expected:   7 * time.Second,
+		},
+		{
+			name:       "http date",
+			retryAfter: now.Add(30 * time.Second).Format(http.TimeFormat),
+			expected:   30 * time.Second,
+		},
+		{
+			name:       "http date in the past",
+			retryAfter: now.Add(-30 * time.Second).Format(http.TimeFormat),
+			// falls back to the backoff duration
+		},
+		{
+			name:       "invalid",
+			retryAfter: "soon",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			l := slogt.New(t)
+
+			mock := &mockRoundTripper{
+				responses: []*http.Response{
+					newRetryAfterResponse(503, tt.retryAfter),
+					newHTTPResponse(200),
+				},
+			}
+
+			var rec sleepRecorder
+
+			rt := &retryTransport{
+				base:       mock,
+				maxRetries: 5,
+				log:        l,
+				sleep:      rec.sleep,
+				now:        func() time.Time { return now },
+			}
+
+			client := &http.Client{Transport: rt}
+			req, _ := http.NewRequest("GET", "http://test", nil)
+
+			resp, err := client.Do(req)
+			assert.NoError(err)
+			assert.Equal(200, resp.StatusCode)
+			assert.Equal(2, mock.callCount)
+
+			if !assert.Len(rec.durations, 1) {
+				return
+			}
+
+			if tt.expected == 0 {
+				// Exponential backoff with jitter.
+				assert.GreaterOrEqual(rec.durations[0], initialBackoffDuration)
+				assert.Less(rec.durations[0], 2*initialBackoffDuration)
+			} else {
+				assert.Equal(tt.expected, rec.durations[0])
+			}
+		})
+	}
+}
+
+func TestRetryAfterExceedsDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	mock := &mockRoundTripper{
+		responses: []*http.Response{
+			newRetryAfterResponse(429, "120"),
+			newHTTPResponse(200),
+		},
+	}
+
+	var rec sleepRecorder
+
+	rt := &retryTransport{
+		base:       mock,
+		maxRetries: 5,
+		log:        l,
+		sleep:      rec.sleep,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	client := &http.Client{Transport: rt}
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://test", nil)
+
+	resp, err := client.Do(req)
+	assert.NoError(err)
+	assert.Equal(429, resp.StatusCode, "last response is returned")
+	assert.Equal(1, mock.callCount)
+	assert.Empty(rec.durations)
+}
+
+func TestRetryStopsOnCanceledContext(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mock := &mockRoundTripper{
+		responses: []*http.Response{
+			newHTTPResponse(500),
+			newHTTPResponse(200),
+		},
+	}
+
+	rt := &retryTransport{
+		base:       mock,
+		maxRetries: 5,
+		log:        l,
+		sleep: func(d time.Duration) {
+			cancel()
+		},
+	}
+
+	client := &http.Client{Transport: rt}
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://test", nil)
+
+	_, err := client.Do(req)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal(1, mock.callCount)
+}
+
+func TestRetryWaitStopsOnCanceledContext(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mock := &mockRoundTripper{
+		responses: []*http.Response{
+			newRetryAfterResponse(429, "3600"),
+			newHTTPResponse(200),
+		},
+	}
+
+	// Use the real wait, which must not block the cancellation.
+	rt := &retryTransport{
+		base:       mock,
+		maxRetries: 5,
+		log:        l,
+	}
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	client := &http.Client{Transport: rt}
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://test", nil)
+
+	start := time.Now()
+	_, err := client.Do(req)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Less(time.Since(start), time.Minute)
+	assert.Equal(1, mock.callCount)
+}
+
+func TestNoRetries(t *testing.T) {
+	for _, code := range []int{200, 500} {
+		t.Run(fmt.Sprint(code), func(t *testing.T) {
+			assert := assert.New(t)
+
+			mock := &mockRoundTripper{
+				responses: []*http.Response{newHTTPResponse(code)},
+			}
+
+			rt := &retryTransport{
+				base:  mock,
+				log:   slogt.New(t),
+				sleep: sleepNoop(t),
+			}
+
+			client := &http.Client{Transport: rt}
+			req, _ := http.NewRequest("GET", "http://test", nil)
+
+			resp, err := client.Do(req)
+			if code == 200 {
+				assert.NoError(err)
+				assert.Equal(200, resp.StatusCode)
+			} else {
+				assert.ErrorContains(err, "all retry attempts failed with status code 500")
+			}
+			assert.Equal(1, mock.callCount, "the request is sent once")
+		})
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	mock := &mockRoundTripper{
+		responses: []*http.Response{
+			newHTTPResponse(502),
+			newHTTPResponse(200),
+		},
+	}
+
+	rt := &retryTransport{
+		base:       mock,
+		maxRetries: 5,
+		log:        l,
+		sleep:      sleepNoop(t),
+	}
+
+	client := &http.Client{Transport: rt}
+	req, _ := http.NewRequest("POST", "http://test", strings.NewReader(`{"id": "42"}`))
+
+	resp, err := client.Do(req)
+	assert.NoError(err)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal(2, mock.callCount)
+	assert.Equal([][]byte{[]byte(`{"id": "42"}`), []byte(`{"id": "42"}`)}, mock.bodies)
+}
+
+func TestNoRetryWithoutReplayableBody(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	mock := &mockRoundTripper{
+		responses: []*http.Response{
+			newHTTPResponse(502),
+			newHTTPResponse(200),
+		},
+	}
+
+	rt := &retryTransport{
+		base:       mock,
+		maxRetries: 5,
+		log:        l,
+		sleep:      sleepNoop(t),
+	}
+
+	client := &http.Client{Transport: rt}
+
+	// The request has no GetBody, since the body type is unknown.
+	body := io.MultiReader(strings.NewReader("data"))
+	req, _ := http.NewRequest("POST", "http://test", body)
+
+	resp, err := client.Do(req)
+	assert.NoError(err)
+	assert.Equal(502, resp.StatusCode, "first response is returned")
+	assert.Equal(1, mock.callCount)
+}
+
+func TestUploadFileRetryReplaysBody(t *testing.T) {
+	content := []byte("some compressed bundle")
+
+	tests := []struct {
+		name string
+		data func(t *testing.T) io.Reader
+	}{
+		{
+			name: "file",
+			data: func(t *testing.T) io.Reader {
+				f, err := os.CreateTemp(t.TempDir(), "bundle")
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { f.Close() })
+
+				if _, err := f.Write(content); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					t.Fatal(err)
+				}
+				return f
+			},
+		},
+		{
+			name: "read seeker",
+			data: func(t *testing.T) io.Reader {
+				return struct{ io.ReadSeeker }{bytes.NewReader(content)}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			l := slogt.New(t)
+
+			mock := &mockRoundTripper{
+				responses: []*http.Response{
+					newHTTPResponse(http.StatusBadGateway),
+					newHTTPResponse(http.StatusServiceUnavailable),
+					newHTTPResponse(http.StatusOK),
+				},
+			}
+
+			rt := &retryTransport{
+				base:       mock,
+				maxRetries: 5,
+				log:        l,
+				sleep:      sleepNoop(t),
+			}
+
+			hc := &http.Client{Transport: rt}
+
+			_, err := uploadFile(context.Background(), hc, "http://test/s3/files/1", tt.data(t), int64(len(content)))
+			assert.NoError(err)
+			assert.Equal(3, mock.callCount)
+			assert.Equal([][]byte{content, content, content}, mock.bodies)
+		})
+	}
+}
+
+func TestUploadFileWithoutReplayableBody(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	mock := &mockRoundTripper{
+		responses: []*http.Response{
+			newHTTPResponse(http.StatusBadGateway),
+			newHTTPResponse(http.StatusOK),
+		},
+	}
+
+	rt := &retryTransport{
+		base:       mock,
+		maxRetries: 5,
+		log:        l,
+		sleep:      sleepNoop(t),
+	}
+
+	hc := &http.Client{Transport: rt}
+
+	data := io.MultiReader(strings.NewReader("data"))
+	_, err := uploadFile(context.Background(), hc, "http://test/s3/files/1", data, 4)
+	assert.ErrorContains(err, "upload failed")
+	assert.Equal(1, mock.callCount)
+}
@@ -0,0 +1,222 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// PredictPlaceholder is replaced by the predicted test selection in the
+// arguments of the test command.
+const PredictPlaceholder = "{}"
+
+type RunOptions struct {
+	Repo string
+
+	WorkDir string
+
+	// Runner is the test runner format used for prediction.
+	Runner string
+
+	// List is the shell command that lists all tests in the runner format.
+	// If empty, no prediction is made and the test command runs as is. If no
+	// test is selected, the test command does not run.
+	List string
+
+	// Command is the test command and its arguments. Each PredictPlaceholder
+	// in the arguments is replaced by the predicted test selection. A
+	// selection of several lines, like the go-test JSON format with one line
+	// per package, runs the test command once per line.
+	Command []string
+
+	// Reports, Include, Exclude, InvalidReports and MaxReports select the
+	// reports uploaded after the test command (see UploadOptions).
+	Reports        []string
+	Include        []string
+	Exclude        []string
+	InvalidReports InvalidReports
+	MaxReports     int
+
+	Debug bool
+
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Client is the used HTTP client for all API requests.
+	Client *http.Client
+}
+
+// Run predicts the tests, runs the test command and uploads its reports. It
+// returns the exit code of the test command, or the first non-zero one if it
+// runs once per selection line. Upload failures are logged, so
+// they do not change the exit code of the test command.
+func Run(l *slog.Logger, env map[string]string, o RunOptions) (int, error) {
+	if len(o.Command) == 0 {
+		return 0, fmt.Errorf("test command is required")
+	}
+
+	runs := [][]string{o.Command}
+
+	if o.List != "" {
+		selection, selected, err := runPredict(l, env, o)
+		if err != nil {
+			return 0, err
+		}
+
+		if selected == 0 {
+			l.Info("no tests selected, skip test command")
+			return 0, nil
+		}
+
+		runs = nil
+		for _, line := range strings.Split(selection, "\n") {
+			runs = append(runs, substitute(o.Command, line))
+		}
+	} else if hasPlaceholder(o.Command) {
+		return 0, fmt.Errorf("list command is required to substitute %q", PredictPlaceholder)
+	}
+
+	started := time.Now().UTC()
+
+	var code int
+	for _, args := range runs {
+		l.Info("run test command", "args", args)
+
+		cmd := testCommand(args, o.WorkDir, o.Stdout, o.Stderr)
+
+		c, err := testCommandExit(l, cmd.Run(), time.Now())
+		if err != nil {
+			return 0, err
+		}
+		// Keep the first failure, but run the remaining selection.
+		if code == 0 {
+			code = c
+		}
+	}
+
+	err := Upload(l, env, UploadOptions{
+		Repo:           o.Repo,
+		Reports:        o.Reports,
+		Include:        o.Include,
+		Exclude:        o.Exclude,
+		InvalidReports: o.InvalidReports,
+		MaxReports:     o.MaxReports,
+		Started:        &started,
+		Debug:          o.Debug,
+		Client:         o.Client,
+	})
+	if err != nil {
+		l.Error("failed to upload test reports", "err", err)
+	}
+
+	return code, nil
+}
+
+// testCommand returns the test command, which reads from stdin.
+func testCommand(args []string, dir string, stdout, stderr io.Writer) *exec.Cmd {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd
+}
+
+// testCommandExit logs and returns the exit code of the finished test command
+// from its wait error.
+func testCommandExit(l *slog.Logger, err error, started time.Time) (int, error) {
+	code, err := exitCode(err)
+	if err != nil {
+		return 0, err
+	}
+
+	l.Info("test command finished",
+		"code", code,
+		"duration", time.Since(started).Round(time.Millisecond),
+	)
+
+	return code, nil
+}
+
+// exitCode returns the exit code of the finished test command. A command
+// killed by a signal returns 128 plus the signal number like shells do.
+func exitCode(err error) (int, error) {
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) {
+		if err != nil {
+			return 0, fmt.Errorf("failed to run test command: %w", err)
+		}
+		return 0, nil
+	}
+
+	if ws, ok := exit.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal()), nil
+	}
+
+	if code := exit.ExitCode(); code >= 0 {
+		return code, nil
+	}
+	return 1, nil
+}
+
+// runPredict runs the list command and returns the formatted test selection
+// and the number of selected tests.
+func runPredict(l *slog.Logger, env map[string]string, o RunOptions) (string, int, error) {
+	l.Info("list tests", "command", o.List)
+
+	list := exec.Command("sh", "-c", o.List)
+	list.Dir = o.WorkDir
+
+	var stderr bytes.Buffer
+	list.Stderr = &stderr
+
+	out, err := list.Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to run list command %q: stderr=%q err=%w", o.List, stderr.String(), err)
+	}
+
+	var selection strings.Builder
+	var selected int
+	if err := Predict(l, env, PredictOptions{
+		Repo:    o.Repo,
+		WorkDir: o.WorkDir,
+		Runner:  o.Runner,
+		Debug:   o.Debug,
+		Stdin:   bytes.NewReader(out),
+		Stdout:  &selection,
+		client:  o.Client,
+
+		selected: &selected,
+	}); err != nil {
+		return "", 0, err
+	}
+
+	// Some formats end with a newline, which is not part of the argument.
+	return strings.TrimSuffix(selection.String(), "\n"), selected, nil
+}
+
+func hasPlaceholder(args []string) bool {
+	for _, arg := range args {
+		if strings.Contains(arg, PredictPlaceholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// substitute replaces the placeholder in the arguments with the selection.
+func substitute(args []string, selection string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = strings.ReplaceAll(arg, PredictPlaceholder, selection)
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package record
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestRun(t *testing.T) {
+	var tests = []struct {
+		name    string
+		options RunOptions
+		code    int
+		stdout  string
+		files   int
+		skipped bool
+		err     string
+	}{
+		{
+			name: "predict-go-test",
+			options: RunOptions{
+				Repo:    "testdata/feature/repo",
+				Runner:  "go-test",
+				List:    `printf 'TestA\nTestB\n'`,
+				Command: []string{"echo", "-run", "{}"},
+				Reports: []string{"testdata/basic/reports"},
+			},
+			stdout: "-run ^(TestA|TestB)$\n",
+			files:  1,
+		},
+		{
+			name: "predict-go-test-packages",
+			options: RunOptions{
+				Repo:   "testdata/feature/repo",
+				Runner: "go-test",
+				List: `printf '%s\n' '{"Action":"output","Package":"example.com/m/a","Output":"TestA\n"}' ` +
+					`'{"Action":"output","Package":"example.com/m/b","Output":"TestB\n"}'`,
+				// The second run fails, since grep selects no line.
+				Command: []string{"sh", "-c", "echo {} | grep -v m/b"},
+				Reports: []string{"testdata/basic/reports"},
+			},
+			code:   1,
+			stdout: "-run ^(TestA)$ example.com/m/a\n",
+			files:  1,
+		},
+		{
+			name: "predict-none",
+			options: RunOptions{
+				Repo:    "testdata/feature/repo",
+				Runner:  "go-test",
+				List:    "true",
+				Command: []string{"echo", "-run", "{}"},
+				Reports: []string{"testdata/basic/reports"},
+			},
+			skipped: true,
+		},
+		{
+			name: "exit-code",
+			options: RunOptions{
+				Repo:    "testdata/feature/repo",
+				Command: []string{"sh", "-c", "echo failed; exit 3"},
+				Reports: []string{"testdata/basic/reports"},
+			},
+			code:   3,
+			stdout: "failed\n",
+			files:  1,
+		},
+		{
+			name: "signal-exit-code",
+			options: RunOptions{
+				Repo:    "testdata/feature/repo",
+				Command: []string{"sh", "-c", "kill -TERM $$"},
+				Reports: []string{"testdata/basic/reports"},
+			},
+			code:  143,
+			files: 1,
+		},
+		{
+			name: "no-reports",
+			options: RunOptions{
+				Command: []string{"true"},
+			},
+		},
+		{
+			name: "missing-list",
+			options: RunOptions{
+				Command: []string{"echo", "{}"},
+			},
+			err: "list command is required",
+		},
+		{
+			name: "failed-list",
+			options: RunOptions{
+				Runner:  "go-test",
+				List:    "exit 1",
+				Command: []string{"echo", "{}"},
+			},
+			err: "failed to run list command",
+		},
+		{
+			name: "unknown-command",
+			options: RunOptions{
+				Command: []string{"./unknown-test-command"},
+			},
+			err: "failed to run test command",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slogt.New(t)
+			assert := assert.New(t)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			var stdout bytes.Buffer
+
+			o := tt.options
+			o.Stdout = &stdout
+			o.Client = http.DefaultClient
+
+			code, err := Run(l, srv.Env, o)
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tt.code, code)
+			assert.Equal(tt.stdout, stdout.String())
+			assert.Len(srv.Files, tt.files)
+
+			if tt.skipped {
+				assert.Empty(srv.Runs)
+				return
+			}
+
+			run, ok := srv.Runs[srv.RunKey()]
+			if assert.True(ok) {
+				assert.NotNil(run.Started)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Cypress parses a listing of spec files with one path per line, e.g. from
+// `find cypress/e2e -name '*.cy.ts'`. The selection is formatted as the
+// comma-separated value of `cypress run --spec`.
+type Cypress struct {
+	options ParserOptions
+	tests   testFiles
+}
+
+func NewCypress(o ParserOptions) Parser {
+	return &Cypress{
+		options: o,
+		tests:   newTestFiles(o),
+	}
+}
+
+func (p *Cypress) Parse(r io.Reader) error {
+	return parseFileList(r, &p.tests)
+}
+
+func (p *Cypress) Format(files []string, w io.Writer) error {
+	if len(files) == 0 {
+		// The spec pattern matches no file, since /dev/null is no directory.
+		_, err := fmt.Fprintln(w, "/dev/null/*")
+		return err
+	}
+
+	var specs []string
+	for _, f := range files {
+		specs = append(specs, relativeFile(f))
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(specs, ","))
+	return err
+}
+
+func (p *Cypress) Files() []string {
+	return p.tests.files
+}
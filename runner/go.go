@@ -2,44 +2,259 @@ package runner
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
 	"strings"
 )
 
+// GoTest parses the output of `go test -list .` and `go test -list . -json`.
+// The JSON output identifies tests as `package/TestName`, so the selection is
+// formatted as one line of `go test` arguments per package:
+//
+//	-run '^(TestA|TestB)$' github.com/org/repo/pkg
+//
+// The plain output is formatted as a single `-run` regexp for all packages.
+// Test names are quoted in both regexps.
 type GoTest struct {
 	options ParserOptions
 	tests   []string
+
+	// packages are the packages of the JSON output.
+	packages []string
+	seen     map[string]bool
+}
+
+// goTestEvent is an event of `go test -json` (see `go doc test2json`).
+type goTestEvent struct {
+	Action  string `json:"Action"`
+	Package string `json:"Package"`
+	Test    string `json:"Test"`
+	Output  string `json:"Output"`
 }
 
+var goTestName = regexp.MustCompile(`^(Test|Benchmark|Example|Fuzz)`)
+
 func NewGoTest(o ParserOptions) Parser {
 	return &GoTest{
 		options: o,
+		seen:    make(map[string]bool),
 	}
 }
 
 func (p *GoTest) Parse(r io.Reader) error {
 	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
 
 	for scanner.Scan() {
 		line := scanner.Text()
+
+		if strings.HasPrefix(line, "{") {
+			var e goTestEvent
+			if err := json.Unmarshal([]byte(line), &e); err == nil {
+				p.parseEvent(e)
+				continue
+			}
+		}
+
 		// Omit any lines with spaces (containing go build output).
 		if strings.Contains(line, " ") {
 			continue
 		}
-		p.tests = append(p.tests, line)
+		p.add(line)
 	}
 
 	return scanner.Err()
 }
 
+func (p *GoTest) parseEvent(e goTestEvent) {
+	if e.Package == "" {
+		return
+	}
+
+	switch {
+	case e.Action == "output" && e.Test == "":
+		// The list output has one test name per line.
+		name := strings.TrimSuffix(e.Output, "\n")
+		if strings.Contains(name, " ") || !goTestName.MatchString(name) {
+			return
+		}
+		p.addPackage(e.Package)
+		p.add(e.Package + "/" + name)
+
+	case e.Action == "run" && e.Test != "":
+		p.addPackage(e.Package)
+		p.add(e.Package + "/" + e.Test)
+	}
+}
+
+func (p *GoTest) add(test string) {
+	if p.seen[test] {
+		return
+	}
+	p.seen[test] = true
+	p.tests = append(p.tests, test)
+}
+
+func (p *GoTest) addPackage(pkg string) {
+	for _, known := range p.packages {
+		if known == pkg {
+			return
+		}
+	}
+	p.packages = append(p.packages, pkg)
+}
+
+// splitTest returns the package and the test name of a test. The package is
+// empty for tests of the plain output.
+func (p *GoTest) splitTest(test string) (string, string) {
+	// Prefer the longest known package, since packages can be nested.
+	pkg := ""
+	for _, known := range p.packages {
+		if strings.HasPrefix(test, known+"/") && len(known) > len(pkg) {
+			pkg = known
+		}
+	}
+	if pkg != "" {
+		return pkg, strings.TrimPrefix(test, pkg+"/")
+	}
+
+	// Otherwise, the test name starts at the first element with a test
+	// prefix.
+	parts := strings.Split(test, "/")
+	for i := 1; i < len(parts); i++ {
+		if goTestName.MatchString(parts[i]) {
+			return strings.Join(parts[:i], "/"), strings.Join(parts[i:], "/")
+		}
+	}
+
+	return "", test
+}
+
+// goTestPackage is the selection of a package.
+type goTestPackage struct {
+	name string
+
+	// tests are the selected top-level tests in order.
+	tests []string
+
+	// subtests are the selected first-level subtests of a top-level test. If
+	// the whole top-level test is selected, it has no subtests.
+	subtests map[string][]string
+	whole    map[string]bool
+}
+
+func (g *goTestPackage) add(test string) {
+	top, sub, hasSub := strings.Cut(test, "/")
+
+	if _, ok := g.subtests[top]; !ok && !g.whole[top] {
+		g.tests = append(g.tests, top)
+		g.subtests[top] = nil
+	}
+
+	if !hasSub {
+		g.whole[top] = true
+		return
+	}
+
+	if g.whole[top] {
+		return
+	}
+
+	// Deeper subtests run with their first-level subtest.
+	sub, _, _ = strings.Cut(sub, "/")
+	for _, s := range g.subtests[top] {
+		if s == sub {
+			return
+		}
+	}
+	g.subtests[top] = append(g.subtests[top], sub)
+}
+
+// patterns returns the `-run` patterns of the package. Tests with selected
+// subtests need their own pattern, since a subtest pattern applies to all
+// matched top-level tests.
+func (g *goTestPackage) patterns() []string {
+	var whole []string
+	var patterns []string
+
+	for _, top := range g.tests {
+		if g.whole[top] {
+			whole = append(whole, regexp.QuoteMeta(top))
+			continue
+		}
+
+		var subs []string
+		for _, s := range g.subtests[top] {
+			subs = append(subs, regexp.QuoteMeta(s))
+		}
+		patterns = append(patterns, fmt.Sprintf("^%s$/^(%s)$", regexp.QuoteMeta(top), strings.Join(subs, "|")))
+	}
+
+	if len(whole) > 0 {
+		patterns = append([]string{fmt.Sprintf("^(%s)$", strings.Join(whole, "|"))}, patterns...)
+	}
+
+	return patterns
+}
+
 func (p *GoTest) Format(files []string, w io.Writer) error {
-	// Create a regexp pattern as test format.
-	out := strings.Join(files, "|")
-	_, err := fmt.Fprintf(w, "^(%s)$", out)
-	return err
+	if len(p.packages) == 0 {
+		// Create a regexp pattern as test format.
+		var names []string
+		for _, f := range files {
+			names = append(names, regexp.QuoteMeta(f))
+		}
+		_, err := fmt.Fprintf(w, "^(%s)$", strings.Join(names, "|"))
+		return err
+	}
+
+	var order []string
+	pkgs := make(map[string]*goTestPackage)
+
+	for _, file := range files {
+		name, test := p.splitTest(file)
+
+		g := pkgs[name]
+		if g == nil {
+			g = &goTestPackage{
+				name:     name,
+				subtests: make(map[string][]string),
+				whole:    make(map[string]bool),
+			}
+			pkgs[name] = g
+			order = append(order, name)
+		}
+		g.add(test)
+	}
+
+	if len(order) == 0 {
+		_, err := fmt.Fprintln(w, "-run "+shellQuote("^()$"))
+		return err
+	}
+
+	for _, name := range order {
+		g := pkgs[name]
+		for _, pattern := range g.patterns() {
+			line := "-run " + shellQuote(pattern)
+			if name != "" {
+				line += " " + name
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
 func (p *GoTest) Files() []string {
 	return p.tests
 }
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
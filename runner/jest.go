@@ -13,7 +13,7 @@ type Jest struct {
 }
 
 type JestTestOutput struct {
-	TestMatch []string `json:"testMatch,omitempty"`
+	TestMatch []string `json:"testMatch"`
 }
 
 func NewJest(o ParserOptions) Parser {
@@ -44,7 +44,8 @@ func (p *Jest) Parse(r io.Reader) error {
 }
 
 func (p *Jest) Format(files []string, w io.Writer) error {
-	var matches []string
+	// An empty testMatch matches no test, unlike a missing one.
+	matches := []string{}
 
 	for _, t := range files {
 		matches = append(matches, t)
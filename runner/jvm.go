@@ -0,0 +1,243 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// JVM parses a listing of JVM test classes, with one fully qualified class
+// name, source file or compiled class file per line. Classes are mapped to
+// their source files, which are sent to the API. The selection is formatted
+// as class filters for Gradle or Maven Surefire.
+type JVM struct {
+	options ParserOptions
+	tests   testFiles
+
+	format func(classes []string, w io.Writer) error
+
+	// classes are the test classes of each test file.
+	classes map[string][]string
+
+	// sources maps class names to source files. It is built on first use.
+	sources map[string][]string
+}
+
+var (
+	// jvmClass matches fully qualified class names, including nested classes.
+	jvmClass = regexp.MustCompile(`^[\p{L}_$][\p{L}\p{N}_$]*(\.[\p{L}_$][\p{L}\p{N}_$]*)*$`)
+
+	// jvmSource matches source files in a source set like `src/test/java`.
+	jvmSource = regexp.MustCompile(`(?:^|/)src/[^/]+/(?:java|kotlin|groovy|scala)/(.+)\.(?:java|kt|groovy|scala)$`)
+
+	// jvmOutputDirs are the class output dirs of Maven and Gradle.
+	jvmOutputDirs = []string{
+		"test-classes/",
+		"classes/java/test/",
+		"classes/kotlin/test/",
+		"classes/groovy/test/",
+		"classes/scala/test/",
+	}
+)
+
+func newJVM(o ParserOptions, format func(classes []string, w io.Writer) error) *JVM {
+	return &JVM{
+		options: o,
+		tests:   newTestFiles(o),
+		format:  format,
+		classes: make(map[string][]string),
+	}
+}
+
+// NewGradle formats the selection as `--tests` filters of `gradle test`.
+func NewGradle(o ParserOptions) Parser {
+	return newJVM(o, func(classes []string, w io.Writer) error {
+		if len(classes) == 0 {
+			// Exclude the test task, since no filter matches nothing.
+			_, err := fmt.Fprintln(w, "-x test")
+			return err
+		}
+
+		var args []string
+		for _, c := range classes {
+			args = append(args, "--tests", shellArg(c))
+		}
+		_, err := fmt.Fprintln(w, strings.Join(args, " "))
+		return err
+	})
+}
+
+// NewMaven formats the selection as `-Dtest=` of Maven Surefire.
+func NewMaven(o ParserOptions) Parser {
+	return newJVM(o, func(classes []string, w io.Writer) error {
+		if len(classes) == 0 {
+			_, err := fmt.Fprintln(w, "-DskipTests")
+			return err
+		}
+		_, err := fmt.Fprintln(w, shellArg("-Dtest="+strings.Join(classes, ",")))
+		return err
+	})
+}
+
+func (p *JVM) Parse(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Gradle test events look like `com.example.FooTest > testBar PASSED`.
+		line, _, _ = strings.Cut(line, " > ")
+
+		// Omit empty lines and any build output.
+		if line == "" || strings.Contains(line, " ") {
+			continue
+		}
+
+		if err := p.parseLine(filepath.ToSlash(line)); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (p *JVM) parseLine(line string) error {
+	if m := jvmSource.FindStringSubmatch(line); m != nil {
+		return p.add(line, strings.ReplaceAll(m[1], "/", "."))
+	}
+
+	class := line
+	if strings.HasSuffix(line, ".class") {
+		class = strings.TrimSuffix(line, ".class")
+		for _, dir := range jvmOutputDirs {
+			if _, after, ok := strings.Cut(class, dir); ok {
+				class = after
+				break
+			}
+		}
+		class = strings.ReplaceAll(class, "/", ".")
+	}
+
+	if !jvmClass.MatchString(class) {
+		return nil
+	}
+
+	// Nested classes are part of the source file of the outer class.
+	class, _, _ = strings.Cut(class, "$")
+
+	sources, err := p.findSources()
+	if err != nil {
+		return err
+	}
+
+	files := sources[class]
+	if len(files) == 0 {
+		// Keep the class as test file, so it is still selected.
+		p.tests.addFile(class)
+		p.addClass(class, class)
+		return nil
+	}
+
+	for _, file := range files {
+		if err := p.add(file, class); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *JVM) add(input, class string) error {
+	file, err := p.tests.add(input)
+	if err != nil {
+		return err
+	}
+	p.addClass(file, class)
+	return nil
+}
+
+func (p *JVM) addClass(file, class string) {
+	for _, c := range p.classes[file] {
+		if c == class {
+			return
+		}
+	}
+	p.classes[file] = append(p.classes[file], class)
+}
+
+// findSources indexes the source files in the workdir by class name.
+func (p *JVM) findSources() (map[string][]string, error) {
+	if p.sources != nil {
+		return p.sources, nil
+	}
+
+	p.sources = make(map[string][]string)
+
+	root := p.options.WorkDir
+	if root == "" {
+		root = "."
+	}
+
+	err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", ".gradle", ".idea", "build", "target", "node_modules":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		slash := filepath.ToSlash(file)
+		m := jvmSource.FindStringSubmatch(slash)
+		if m == nil || strings.Contains("/"+slash, "/src/main/") {
+			return nil
+		}
+
+		class := strings.ReplaceAll(m[1], "/", ".")
+		p.sources[class] = append(p.sources[class], file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find test sources: %w", err)
+	}
+
+	return p.sources, nil
+}
+
+func (p *JVM) Format(files []string, w io.Writer) error {
+	var classes []string
+	seen := make(map[string]bool)
+
+	for _, file := range files {
+		names := p.classes[file]
+		if names == nil {
+			// Files that were not parsed are mapped by their path.
+			if m := jvmSource.FindStringSubmatch(file); m != nil {
+				names = []string{strings.ReplaceAll(m[1], "/", ".")}
+			} else {
+				names = []string{strings.TrimSuffix(path.Base(file), path.Ext(file))}
+			}
+		}
+
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				classes = append(classes, name)
+			}
+		}
+	}
+
+	return p.format(classes, w)
+}
+
+func (p *JVM) Files() []string {
+	return p.tests.files
+}
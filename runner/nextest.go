@@ -0,0 +1,196 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Nextest parses the output of `cargo nextest list --message-format json`.
+// Tests are predicted by their source file where it can be derived from the
+// binary and the test module path, otherwise by `binary-id::test-name`. The
+// selection is formatted as a nextest filterset for `cargo nextest run -E`.
+type Nextest struct {
+	options ParserOptions
+	tests   testFiles
+
+	// cases are the test cases of each test file.
+	cases map[string][]nextestCase
+
+	// counts are the number of test cases of each binary.
+	counts map[string]int
+}
+
+type nextestCase struct {
+	binaryId string
+	name     string
+}
+
+type nextestList struct {
+	RustSuites map[string]nextestSuite `json:"rust-suites"`
+}
+
+type nextestSuite struct {
+	BinaryId   string                     `json:"binary-id"`
+	BinaryName string                     `json:"binary-name"`
+	Kind       string                     `json:"kind"`
+	Cwd        string                     `json:"cwd"`
+	Testcases  map[string]json.RawMessage `json:"testcases"`
+}
+
+func NewNextest(o ParserOptions) Parser {
+	return &Nextest{
+		options: o,
+		tests:   newTestFiles(o),
+		cases:   make(map[string][]nextestCase),
+		counts:  make(map[string]int),
+	}
+}
+
+func (p *Nextest) Parse(r io.Reader) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// Omit any output before the JSON, like cargo build output.
+	start := bytes.IndexByte(buf, '{')
+	if start < 0 {
+		return nil
+	}
+
+	var list nextestList
+	if err := json.Unmarshal(buf[start:], &list); err != nil {
+		return fmt.Errorf("failed to decode nextest list: %w", err)
+	}
+
+	// Map keys are unordered, so sort the suites and test cases.
+	for _, id := range slices.Sorted(maps.Keys(list.RustSuites)) {
+		suite := list.RustSuites[id]
+		if suite.BinaryId == "" {
+			suite.BinaryId = id
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(suite.Testcases)) {
+			if err := p.add(suite, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Nextest) add(suite nextestSuite, name string) error {
+	c := nextestCase{binaryId: suite.BinaryId, name: name}
+	p.counts[c.binaryId]++
+
+	file := c.binaryId + "::" + name
+	if source := nextestSource(suite, name); source != "" {
+		f, err := p.tests.add(source)
+		if err != nil {
+			return err
+		}
+		file = f
+	} else {
+		p.tests.addFile(file)
+	}
+
+	p.cases[file] = append(p.cases[file], c)
+	return nil
+}
+
+// nextestSource returns the source file of a test case if it exists.
+func nextestSource(suite nextestSuite, name string) string {
+	if suite.Cwd == "" {
+		return ""
+	}
+
+	var candidates []string
+
+	switch suite.Kind {
+	case "test", "bench":
+		// Integration tests are compiled per file.
+		candidates = append(candidates,
+			filepath.Join(suite.Cwd, suite.Kind+"s", suite.BinaryName+".rs"),
+			filepath.Join(suite.Cwd, suite.Kind+"s", suite.BinaryName, "main.rs"),
+		)
+	case "lib", "proc-macro", "bin":
+		// Unit tests are in the module of their path, e.g. `parser::tests::a`
+		// is in `src/parser.rs` or `src/parser/mod.rs`.
+		modules := strings.Split(name, "::")
+		modules = modules[:len(modules)-1]
+
+		for i := len(modules); i > 0; i-- {
+			dir := filepath.Join(append([]string{suite.Cwd, "src"}, modules[:i]...)...)
+			candidates = append(candidates, dir+".rs", filepath.Join(dir, "mod.rs"))
+		}
+
+		if suite.Kind == "bin" {
+			candidates = append(candidates,
+				filepath.Join(suite.Cwd, "src", "bin", suite.BinaryName+".rs"),
+				filepath.Join(suite.Cwd, "src", "main.rs"),
+			)
+		} else {
+			candidates = append(candidates, filepath.Join(suite.Cwd, "src", "lib.rs"))
+		}
+	}
+
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && info.Mode().IsRegular() {
+			return c
+		}
+	}
+	return ""
+}
+
+func (p *Nextest) Format(files []string, w io.Writer) error {
+	var order []string
+	selected := make(map[string][]string)
+
+	for _, file := range files {
+		for _, c := range p.cases[file] {
+			if _, ok := selected[c.binaryId]; !ok {
+				order = append(order, c.binaryId)
+			}
+			if !slices.Contains(selected[c.binaryId], c.name) {
+				selected[c.binaryId] = append(selected[c.binaryId], c.name)
+			}
+		}
+	}
+
+	if len(order) == 0 {
+		_, err := fmt.Fprintln(w, "none()")
+		return err
+	}
+
+	var exprs []string
+	for _, id := range order {
+		names := selected[id]
+
+		// Select the whole binary if all of its tests are selected.
+		if len(names) == p.counts[id] {
+			exprs = append(exprs, fmt.Sprintf("binary_id(=%s)", id))
+			continue
+		}
+
+		var tests []string
+		for _, name := range names {
+			tests = append(tests, fmt.Sprintf("test(=%s)", name))
+		}
+		exprs = append(exprs, fmt.Sprintf("(binary_id(=%s) & (%s))", id, strings.Join(tests, " | ")))
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(exprs, " | "))
+	return err
+}
+
+func (p *Nextest) Files() []string {
+	return p.tests.files
+}
@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"path/filepath"
@@ -13,13 +14,28 @@ type ParserOptions struct {
 
 type Parser interface {
 	Parse(r io.Reader) error
+
+	// Format writes the selected files in the runner format. An empty
+	// selection, e.g. of a shard without tests, is formatted as arguments
+	// that run no tests, never as an empty line that runs all of them.
 	Format(files []string, w io.Writer) error
+
 	Files() []string
 }
 
 var parsers = map[string]func(o ParserOptions) Parser{
-	"go-test": NewGoTest,
-	"jest":    NewJest,
+	"go-test":    NewGoTest,
+	"jest":       NewJest,
+	"pytest":     NewPytest,
+	"playwright": NewPlaywright,
+	"cypress":    NewCypress,
+	"gradle":     NewGradle,
+	"maven":      NewMaven,
+	"rspec":      NewRSpec,
+	"minitest":   NewMinitest,
+	"nextest":    NewNextest,
+	"vitest":     NewVitest,
+	"mocha":      NewMocha,
 }
 
 func New(name string, o ParserOptions) (Parser, error) {
@@ -30,7 +46,12 @@ func New(name string, o ParserOptions) (Parser, error) {
 	return p(o), nil
 }
 
+// parseFile resolves the symlinks of a test file and returns its path with
+// the workdir prefix trimmed. Relative paths are relative to the workdir.
 func parseFile(input string, opt ParserOptions) (string, error) {
+	if !filepath.IsAbs(input) {
+		input = filepath.Join(opt.WorkDir, input)
+	}
 	file, err := filepath.EvalSymlinks(input)
 	if err != nil {
 		return file, fmt.Errorf("failed to eval symlink of input file %q: %w", input, err)
@@ -38,3 +59,87 @@ func parseFile(input string, opt ParserOptions) (string, error) {
 	file = strings.TrimPrefix(file, opt.WorkDir)
 	return file, nil
 }
+
+// testFiles collects the unique test files of a parser in order.
+type testFiles struct {
+	options ParserOptions
+	files   []string
+	seen    map[string]bool
+}
+
+func newTestFiles(o ParserOptions) testFiles {
+	return testFiles{
+		options: o,
+		seen:    make(map[string]bool),
+	}
+}
+
+// add adds the test file after parseFile and returns it.
+func (t *testFiles) add(input string) (string, error) {
+	file, err := parseFile(input, t.options)
+	if err != nil {
+		return "", err
+	}
+	t.addFile(file)
+	return file, nil
+}
+
+// addFile adds the file as is.
+func (t *testFiles) addFile(file string) {
+	if t.seen[file] {
+		return
+	}
+	t.seen[file] = true
+	t.files = append(t.files, file)
+}
+
+// relativeFile returns a file of parseFile relative to the workdir, so it can
+// be passed to the test runner.
+func relativeFile(file string) string {
+	return strings.TrimPrefix(file, "/")
+}
+
+// shellArg quotes s for POSIX shells if it contains special characters.
+func shellArg(s string) string {
+	if s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r))
+	}) {
+		return s
+	}
+	return shellQuote(s)
+}
+
+// parseFileList adds the test files of a listing with one path per line.
+func parseFileList(r io.Reader, tests *testFiles) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Omit empty lines and any output that is not a path.
+		if line == "" || strings.Contains(line, " ") {
+			continue
+		}
+		if _, err := tests.add(line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// formatArgs writes the files relative to the workdir as shell arguments. An
+// empty selection is written as the none arguments.
+func formatArgs(files []string, none string, w io.Writer) error {
+	if len(files) == 0 {
+		_, err := fmt.Fprintln(w, none)
+		return err
+	}
+
+	var args []string
+	for _, f := range files {
+		args = append(args, shellArg(relativeFile(f)))
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(args, " "))
+	return err
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Playwright parses the JSON report of `npx playwright test --list
+// --reporter=json`. The selection is formatted as spec file arguments.
+type Playwright struct {
+	options ParserOptions
+	tests   testFiles
+}
+
+type playwrightReport struct {
+	Config struct {
+		RootDir string `json:"rootDir"`
+	} `json:"config"`
+	Suites []playwrightSuite `json:"suites"`
+}
+
+type playwrightSuite struct {
+	// File is relative to the root dir of the config.
+	File   string            `json:"file"`
+	Suites []playwrightSuite `json:"suites"`
+}
+
+func NewPlaywright(o ParserOptions) Parser {
+	return &Playwright{
+		options: o,
+		tests:   newTestFiles(o),
+	}
+}
+
+func (p *Playwright) Parse(r io.Reader) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// Omit any output before the report, like npx warnings.
+	start := bytes.IndexByte(buf, '{')
+	if start < 0 {
+		return nil
+	}
+
+	var report playwrightReport
+	if err := json.Unmarshal(buf[start:], &report); err != nil {
+		return fmt.Errorf("failed to decode playwright report: %w", err)
+	}
+
+	root := report.Config.RootDir
+	if root == "" {
+		root = p.options.WorkDir
+	}
+
+	return p.addSuites(root, report.Suites)
+}
+
+func (p *Playwright) addSuites(root string, suites []playwrightSuite) error {
+	for _, s := range suites {
+		if s.File != "" {
+			if _, err := p.tests.add(filepath.Join(root, s.File)); err != nil {
+				return err
+			}
+		}
+		if err := p.addSuites(root, s.Suites); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Playwright) Format(files []string, w io.Writer) error {
+	return formatArgs(files, "--grep '^$'", w)
+}
+
+func (p *Playwright) Files() []string {
+	return p.tests.files
+}
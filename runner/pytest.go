@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Pytest parses the node ids of `pytest --collect-only -q`, like
+// `tests/test_api.py::TestX::test_y`. The tests are predicted by file and the
+// selection is formatted as file arguments for pytest.
+type Pytest struct {
+	options ParserOptions
+	tests   testFiles
+}
+
+// pytestCount matches the file counts of `pytest --collect-only -qq`.
+var pytestCount = regexp.MustCompile(`^(\S+\.py): \d+$`)
+
+func NewPytest(o ParserOptions) Parser {
+	return &Pytest{
+		options: o,
+		tests:   newTestFiles(o),
+	}
+}
+
+func (p *Pytest) Parse(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		var path string
+		if file, _, ok := strings.Cut(line, "::"); ok && !strings.HasPrefix(line, " ") {
+			path = file
+		} else if m := pytestCount.FindStringSubmatch(line); m != nil {
+			path = m[1]
+		} else {
+			// Omit any other lines, like warnings and the summary.
+			continue
+		}
+
+		if _, err := p.tests.add(path); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (p *Pytest) Format(files []string, w io.Writer) error {
+	// The keyword expression matches no test.
+	return formatArgs(files, "-k 'x and not x'", w)
+}
+
+func (p *Pytest) Files() []string {
+	return p.tests.files
+}
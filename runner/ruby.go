@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// RSpec parses the JSON output of `rspec --dry-run --format json`. The
+// selection is formatted as spec file arguments.
+type RSpec struct {
+	options ParserOptions
+	tests   testFiles
+}
+
+type rspecOutput struct {
+	Examples []struct {
+		FilePath string `json:"file_path"`
+	} `json:"examples"`
+}
+
+func NewRSpec(o ParserOptions) Parser {
+	return &RSpec{
+		options: o,
+		tests:   newTestFiles(o),
+	}
+}
+
+func (p *RSpec) Parse(r io.Reader) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// Omit any output before the JSON, like deprecation warnings.
+	start := bytes.Index(buf, []byte(`{"version"`))
+	if start < 0 {
+		start = bytes.IndexByte(buf, '{')
+	}
+	if start < 0 {
+		return nil
+	}
+
+	var out rspecOutput
+	dec := json.NewDecoder(bytes.NewReader(buf[start:]))
+	if err := dec.Decode(&out); err != nil {
+		return fmt.Errorf("failed to decode rspec output: %w", err)
+	}
+
+	for _, e := range out.Examples {
+		if e.FilePath == "" {
+			continue
+		}
+		if _, err := p.tests.add(e.FilePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *RSpec) Format(files []string, w io.Writer) error {
+	// No example has the tag.
+	return formatArgs(files, "--tag testlab_none", w)
+}
+
+func (p *RSpec) Files() []string {
+	return p.tests.files
+}
+
+// Minitest parses a listing of test files with one path per line, e.g. from
+// `find test -name '*_test.rb'`. The selection is formatted as test file
+// arguments for `rails test`.
+type Minitest struct {
+	options ParserOptions
+	tests   testFiles
+}
+
+func NewMinitest(o ParserOptions) Parser {
+	return &Minitest{
+		options: o,
+		tests:   newTestFiles(o),
+	}
+}
+
+func (p *Minitest) Parse(r io.Reader) error {
+	return parseFileList(r, &p.tests)
+}
+
+func (p *Minitest) Format(files []string, w io.Writer) error {
+	return formatArgs(files, "-n '/^$/'", w)
+}
+
+func (p *Minitest) Files() []string {
+	return p.tests.files
+}
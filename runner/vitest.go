@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Vitest parses the JSON output of `vitest list --json`. The selection is
+// formatted as test file arguments for `vitest run`.
+type Vitest struct {
+	options ParserOptions
+	tests   testFiles
+}
+
+type vitestTest struct {
+	File string `json:"file"`
+}
+
+func NewVitest(o ParserOptions) Parser {
+	return &Vitest{
+		options: o,
+		tests:   newTestFiles(o),
+	}
+}
+
+func (p *Vitest) Parse(r io.Reader) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// Omit any output before the JSON, like vite warnings.
+	start := bytes.IndexByte(buf, '[')
+	if start < 0 {
+		return nil
+	}
+
+	var tests []vitestTest
+	if err := json.Unmarshal(buf[start:], &tests); err != nil {
+		return fmt.Errorf("failed to decode vitest list: %w", err)
+	}
+
+	for _, t := range tests {
+		if t.File == "" {
+			continue
+		}
+		if _, err := p.tests.add(t.File); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Vitest) Format(files []string, w io.Writer) error {
+	return formatArgs(files, "--testNamePattern '^$'", w)
+}
+
+func (p *Vitest) Files() []string {
+	return p.tests.files
+}
+
+// Mocha parses a listing of spec files with one path per line. The selection
+// is formatted as spec file arguments for mocha.
+type Mocha struct {
+	options ParserOptions
+	tests   testFiles
+}
+
+func NewMocha(o ParserOptions) Parser {
+	return &Mocha{
+		options: o,
+		tests:   newTestFiles(o),
+	}
+}
+
+func (p *Mocha) Parse(r io.Reader) error {
+	return parseFileList(r, &p.tests)
+}
+
+func (p *Mocha) Format(files []string, w io.Writer) error {
+	return formatArgs(files, "--grep '^$'", w)
+}
+
+func (p *Mocha) Files() []string {
+	return p.tests.files
+}
@@ -0,0 +1,90 @@
+package record
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// validateShard checks the 1-based shard index. A zero total disables
+// sharding.
+func validateShard(index, total int) error {
+	if total == 0 && index == 0 {
+		return nil
+	}
+	if total < 1 || index < 1 || index > total {
+		return fmt.Errorf("invalid shard %d/%d: index must be between 1 and total", index, total)
+	}
+	return nil
+}
+
+// shardTests returns the tests of the 1-based shard index in their original
+// order. The tests are partitioned deterministically by assigning the longest
+// tests first to the shard with the least total duration. Tests without a
+// duration are weighted with the mean duration, so without any durations the
+// shards are balanced by file count.
+func shardTests(tests []predictedTest, index, total int) ([]predictedTest, float64) {
+	if total <= 1 {
+		return tests, 0
+	}
+
+	var known float64
+	var count int
+	for _, t := range tests {
+		if t.Duration != nil {
+			known += *t.Duration
+			count++
+		}
+	}
+
+	mean := 1.0
+	if count > 0 {
+		mean = known / float64(count)
+	}
+
+	weight := func(t predictedTest) float64 {
+		if t.Duration != nil {
+			return *t.Duration
+		}
+		return mean
+	}
+
+	order := make([]int, len(tests))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		if c := cmp.Compare(weight(tests[b]), weight(tests[a])); c != 0 {
+			return c
+		}
+		return cmp.Compare(tests[a].Path, tests[b].Path)
+	})
+
+	loads := make([]float64, total)
+	shard := make([]int, len(tests))
+
+	for _, i := range order {
+		least := 0
+		for s := 1; s < total; s++ {
+			if loads[s] < loads[least] {
+				least = s
+			}
+		}
+		shard[i] = least
+		loads[least] += weight(tests[i])
+	}
+
+	var out []predictedTest
+	for i, t := range tests {
+		if shard[i] == index-1 {
+			out = append(out, t)
+		}
+	}
+
+	var duration float64
+	if count > 0 {
+		duration = loads[index-1]
+	}
+
+	return out, duration
+}
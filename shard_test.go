@@ -0,0 +1,164 @@
+package record
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+func TestShardTests(t *testing.T) {
+	var tests = []struct {
+		name     string
+		tests    []predictedTest
+		total    int
+		expected [][]string
+		duration []float64
+	}{
+		{
+			name:     "count",
+			tests:    []predictedTest{{Path: "a"}, {Path: "b"}, {Path: "c"}, {Path: "d"}, {Path: "e"}},
+			total:    2,
+			expected: [][]string{{"a", "c", "e"}, {"b", "d"}},
+			duration: []float64{0, 0},
+		},
+		{
+			name: "duration",
+			tests: []predictedTest{
+				{Path: "a", Duration: float(1)},
+				{Path: "b", Duration: float(10)},
+				{Path: "c", Duration: float(4)},
+				{Path: "d", Duration: float(5)},
+			},
+			total:    2,
+			expected: [][]string{{"b"}, {"a", "c", "d"}},
+			duration: []float64{10, 10},
+		},
+		{
+			name: "partial-duration",
+			tests: []predictedTest{
+				{Path: "a"},
+				{Path: "b", Duration: float(6)},
+				{Path: "c", Duration: float(2)},
+			},
+			total:    2,
+			expected: [][]string{{"b"}, {"a", "c"}},
+			duration: []float64{6, 6},
+		},
+		{
+			name:     "more-shards-than-tests",
+			tests:    []predictedTest{{Path: "a"}},
+			total:    3,
+			expected: [][]string{{"a"}, nil, nil},
+			duration: []float64{0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var all []string
+			for i := 1; i <= tt.total; i++ {
+				shard, d := shardTests(tt.tests, i, tt.total)
+				assert.Equal(tt.expected[i-1], testPaths(shard), "shard %d", i)
+				assert.Equal(tt.duration[i-1], d, "shard %d", i)
+
+				// The partition does not change between calls.
+				again, _ := shardTests(tt.tests, i, tt.total)
+				assert.Equal(shard, again)
+
+				all = append(all, testPaths(shard)...)
+			}
+
+			assert.ElementsMatch(testPaths(tt.tests), all)
+		})
+	}
+}
+
+func TestValidateShard(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(validateShard(0, 0))
+	assert.NoError(validateShard(1, 1))
+	assert.NoError(validateShard(3, 3))
+
+	assert.ErrorContains(validateShard(0, 2), "invalid shard 0/2")
+	assert.ErrorContains(validateShard(3, 2), "invalid shard 3/2")
+	assert.ErrorContains(validateShard(1, 0), "invalid shard 1/0")
+	assert.ErrorContains(validateShard(-1, -1), "invalid shard -1/-1")
+}
+
+func TestPredictShard(t *testing.T) {
+	var tests = []struct {
+		name     string
+		index    int
+		total    int
+		expected string
+		err      string
+	}{
+		{
+			name:     "first",
+			index:    1,
+			total:    2,
+			expected: "^(TestA|TestSlow)$",
+		},
+		{
+			name:     "second",
+			index:    2,
+			total:    2,
+			expected: "^(TestB|TestC)$",
+		},
+		{
+			name:  "invalid",
+			index: 3,
+			total: 2,
+			err:   "invalid shard 3/2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slogt.New(t)
+			assert := assert.New(t)
+
+			srv := fake.NewServer(t, l, client.Github)
+			defer srv.Close()
+
+			srv.Handlers.Predict = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"testFiles": [
+					{"path": "TestA", "duration": 3},
+					{"path": "TestSlow", "duration": 9},
+					{"path": "TestB", "duration": 4},
+					{"path": "TestC"}
+				]}`))
+			}
+
+			var out bytes.Buffer
+			opt := PredictOptions{
+				Repo:       "testdata/feature/repo",
+				Runner:     "go-test",
+				ShardIndex: tt.index,
+				ShardTotal: tt.total,
+				Stdin:      strings.NewReader("TestA\nTestB\nTestC\nTestSlow\n"),
+				Stdout:     &out,
+			}
+
+			err := Predict(l, srv.Env, opt)
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tt.expected, out.String())
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package tar
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// File is a tarball entry whose content is opened on demand. If Size is
+// negative, the content is read into memory before its header is written.
+// This is meant for small generated files that depend on previous entries.
+type File struct {
+	Name string
+	Size int64
+	Open func() (io.ReadCloser, error)
+}
+
+// Stream writes a tarball of the files into out. Only one file is open at a
+// time and its content is copied without buffering the whole file.
+func Stream(files []File, out io.Writer) error {
+	tw := tar.NewWriter(out)
+
+	for _, f := range files {
+		if f.Size < 0 {
+			buffered, err := bufferFile(f)
+			if err != nil {
+				return err
+			}
+			f = buffered
+		}
+
+		header := &tar.Header{
+			Name: f.Name,
+			Mode: 0600,
+			Size: f.Size,
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar header: %w", err)
+		}
+
+		if err := copyFile(tw, f); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to close tar writer: %w", err)
+	}
+
+	return nil
+}
+
+func copyFile(w io.Writer, f File) error {
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", f.Name, err)
+	}
+	defer r.Close()
+
+	// Copy exactly the size written in the header, even if the file grew
+	// in the meantime.
+	if _, err := io.CopyN(w, r, f.Size); err != nil {
+		return fmt.Errorf("failed to write file content of %q: %w", f.Name, err)
+	}
+
+	return nil
+}
+
+// bufferFile reads the content of the file into memory to get its size.
+func bufferFile(f File) (File, error) {
+	r, err := f.Open()
+	if err != nil {
+		return f, fmt.Errorf("failed to open %q: %w", f.Name, err)
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return f, fmt.Errorf("failed to read %q: %w", f.Name, err)
+	}
+
+	f.Size = int64(len(content))
+	f.Open = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	return f, nil
+}
@@ -0,0 +1,31 @@
+package tar
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+)
+
+// Walk calls fn for each regular file of the tarball in order. The reader
+// passed to fn is only valid until fn returns.
+func Walk(r io.Reader, fn func(name string, size int64, r io.Reader) error) error {
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tarball: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := fn(header.Name, header.Size, tr); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,3 @@
+describe('orders', () => {
+  it('creates', () => {});
+});
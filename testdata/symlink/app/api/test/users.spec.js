@@ -0,0 +1,3 @@
+describe('users', () => {
+  it('lists', () => {});
+});
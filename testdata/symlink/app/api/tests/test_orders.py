@@ -0,0 +1,3 @@
+class TestOrders:
+    def test_create(self):
+        pass
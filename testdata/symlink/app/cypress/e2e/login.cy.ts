@@ -0,0 +1,3 @@
+describe('login', () => {
+  it('logs in', () => {});
+});
@@ -0,0 +1,3 @@
+describe('signup', () => {
+  it('signs up', () => {});
+});
@@ -0,0 +1,4 @@
+RSpec.describe Order do
+  it "totals" do
+  end
+end
@@ -0,0 +1,4 @@
+RSpec.describe User do
+  it "is valid" do
+  end
+end
@@ -0,0 +1,2 @@
+class OrdersControllerTest < ActionDispatch::IntegrationTest
+end
@@ -0,0 +1,2 @@
+class UsersControllerTest < ActionDispatch::IntegrationTest
+end
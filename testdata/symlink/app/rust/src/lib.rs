@@ -0,0 +1,7 @@
+pub mod parser;
+
+#[cfg(test)]
+mod tests {
+    #[test]
+    fn it_works() {}
+}
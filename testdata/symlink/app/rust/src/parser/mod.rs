@@ -0,0 +1,8 @@
+#[cfg(test)]
+mod tests {
+    #[test]
+    fn parses() {}
+
+    #[test]
+    fn fails() {}
+}
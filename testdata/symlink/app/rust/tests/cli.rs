@@ -0,0 +1,2 @@
+#[test]
+fn end_to_end() {}
@@ -0,0 +1,5 @@
+package com.example;
+
+class UserServiceTest {
+    static class Nested {}
+}
@@ -1,12 +1,12 @@
 package record
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/testlabtools/record/client"
@@ -18,19 +18,54 @@ type UploadOptions struct {
 	// Repo is the path to the git repository directory.
 	Repo string
 
-	// Reports is the path to the JUnit reports directory.
-	Reports string
+	// Reports are the JUnit report files, directories or glob patterns. Glob
+	// patterns support `**` to match any number of directories.
+	//
+	// Reports used to be a single directory string. Callers of the former
+	// API pass the directory as the only element.
+	Reports []string
+
+	// Include and Exclude are glob patterns to filter the found report files.
+	// Patterns without a slash match the file name, other patterns match the
+	// path relative to the report path. If Include is empty, all found files
+	// are included.
+	Include []string
+	Exclude []string
+
+	// InvalidReports is the policy for XML report files that are not valid
+	// JUnit XML. If empty, invalid reports are logged and omitted. Other
+	// files are uploaded without validation.
+	InvalidReports InvalidReports
 
 	// Started is the start time of the run. If nil, `NOW()` is returned from
 	// the API.
 	Started *time.Time
 
 	// MaxReports is the maximum number of reports that can be found in the
-	// reports directory. If it exceeds the threshold, an error is returned.
+	// reports paths. If it exceeds the threshold, an error is returned.
 	//
 	// If omitted (or zero), DefaulMaxReports is used.
 	MaxReports int
 
+	// Output is the path of a bundle file. If set, the bundle and its run
+	// manifest are written to disk instead of uploaded, so they can be
+	// replayed later using From.
+	Output string
+
+	// From is the path of a bundle file written with Output. If set, the
+	// saved bundle is uploaded instead of collecting reports.
+	From string
+
+	// PartSize is the part size of multipart uploads. Bundles larger than
+	// PartSize are uploaded in parts, if the server supports it. If zero,
+	// bundles are uploaded with a single request.
+	//
+	// Multipart uploads are experimental, since the API does not serve them
+	// yet. Only uploads of a bundle file From can be resumed, since their
+	// progress is stored next to the bundle file. Other uploads start again
+	// with the first part.
+	PartSize int64
+
 	// Debug enables verbose log messages. By default (false), only messages
 	// with level info are visible.
 	Debug bool
@@ -58,14 +93,60 @@ func (u *api) createRun(ctx context.Context, body client.CreateRunJSONRequestBod
 	}
 }
 
-// uploadRun uploads the data to the pre-signed URL of the run file.
-func (u *api) uploadRunFile(ctx context.Context, run *client.CIRunResponse, data io.Reader) error {
+// uploadRunFile uploads the data to the pre-signed URL of the run file. Data
+// larger than the part size is uploaded in parts, unless the server does not
+// support multipart uploads.
+func (u *api) uploadRunFile(ctx context.Context, run *client.CIRunResponse, f fileUpload) error {
 	runId := run.Id
 
+	var fileId string
+
+	if data, ok := f.multipart(); ok {
+		u.log.Debug("upload run file in parts", "size", f.size, "partSize", f.partSize)
+
+		id, err := u.uploadMultipart(ctx, runId, f, data)
+		if multipartUnsupported(err) {
+			u.log.Warn("server does not support multipart uploads, upload with a single request", "err", err)
+			removeMultipartState(u.log, f.stateFile)
+		} else if err != nil {
+			return fmt.Errorf("failed to upload file: %w", err)
+		}
+		fileId = id
+	}
+
+	if fileId == "" {
+		id, err := u.uploadSingle(ctx, runId, f)
+		if err != nil {
+			return err
+		}
+		fileId = id
+	}
+
+	u.log.Info("upload successful", "fileId", fileId)
+
+	// Mark file upload as completed
+	resp, err := u.api.UpdateRunFileInfoWithResponse(ctx, runId, fileId, client.UpdateRunFileInfoJSONRequestBody{
+		UploadStatus: client.UploadCompleted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update file info %w", err)
+	}
+
+	code := resp.StatusCode()
+	if code != http.StatusOK {
+		return fmt.Errorf("update file info returned invalid status code: %d", code)
+	}
+
+	return nil
+}
+
+// uploadSingle uploads the data with a single request and returns the run
+// file id.
+func (u *api) uploadSingle(ctx context.Context, runId string, f fileUpload) (string, error) {
 	// Get pre-signed url for the new run file.
 	upload, err := u.api.GetRunFileUploadUrlWithResponse(ctx, runId)
 	if err != nil {
-		return fmt.Errorf("failed to get run file upload url: %w", err)
+		return "", fmt.Errorf("failed to get run file upload url: %w", err)
 	}
 
 	code := upload.StatusCode()
@@ -77,61 +158,123 @@ func (u *api) uploadRunFile(ctx context.Context, run *client.CIRunResponse, data
 		fileId = upload.JSON201.Id
 		url = upload.JSON201.Url
 	default:
-		return fmt.Errorf("create run returned invalid status code: %d", code)
+		return "", fmt.Errorf("create run returned invalid status code: %d", code)
 	}
 
 	u.log.Debug("got run file upload", "fileId", fileId, "url", url)
 
 	// Upload data to pre-signed url.
-	if err := uploadFile(ctx, u.hc, url, data); err != nil {
-		return fmt.Errorf("failed to upload file: %w", err)
+	if f.open != nil {
+		_, err = uploadStream(ctx, u.hc, url, f.open, f.size)
+	} else {
+		_, err = uploadFile(ctx, u.hc, url, f.data, f.size)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
 	}
 
-	u.log.Info("upload successful", "fileId", fileId)
+	return fileId, nil
+}
 
-	// Mark file upload as completed
-	resp, err := u.api.UpdateRunFileInfoWithResponse(ctx, runId, fileId, client.UpdateRunFileInfoJSONRequestBody{
-		UploadStatus: client.UploadCompleted,
-	})
+// uploadFile uploads the compressed data to the specified URL. The size is
+// sent as Content-Length, since pre-signed storage URLs reject chunked
+// uploads. If data is seekable, the upload can be retried. It returns the
+// response header, which contains the ETag of the upload.
+func uploadFile(ctx context.Context, client *http.Client, url string, data io.Reader, size int64) (http.Header, error) {
+	payload, getBody, err := replayableBody(data, size)
 	if err != nil {
-		return fmt.Errorf("failed to update file info %w", err)
+		return nil, err
 	}
 
-	code = resp.StatusCode()
-	if code != http.StatusOK {
-		return fmt.Errorf("update file info returned invalid status code: %d", code)
+	return putFile(ctx, client, url, payload, getBody, size)
+}
+
+// uploadStream uploads the data of open to the specified URL. Each attempt
+// opens the data again, so the upload can be retried. The data must have the
+// given size.
+func uploadStream(ctx context.Context, client *http.Client, url string, open func() (io.ReadCloser, error), size int64) (http.Header, error) {
+	payload, err := open()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return putFile(ctx, client, url, payload, open, size)
 }
 
-// uploadFile uploads the compressed data to the specified URL.
-func uploadFile(ctx context.Context, client *http.Client, url string, data io.Reader) error {
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, data)
+func putFile(ctx context.Context, client *http.Client, url string, payload io.Reader, getBody func() (io.ReadCloser, error), size int64) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, payload)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
+	req.ContentLength = size
+	req.GetBody = getBody
+
 	req.Header.Set("Content-Type", "application/zstd")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("upload failed: %s", string(body))
+		return nil, fmt.Errorf("upload failed: %s", string(body))
 	}
 
-	return nil
+	return resp.Header, nil
 }
 
-func Upload(l *slog.Logger, osEnv map[string]string, o UploadOptions) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
-	defer cancel()
+// replayableBody returns the request body of data and a function to rebuild
+// it for retries. The returned body does not close data, since the transport
+// closes request bodies after each attempt. If data is not seekable, the
+// function is nil.
+func replayableBody(data io.Reader, size int64) (io.Reader, func() (io.ReadCloser, error), error) {
+	seeker, ok := data.(io.Seeker)
+	if !ok {
+		return data, nil, nil
+	}
+
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get upload offset: %w", err)
+	}
+
+	// Prefer independent section readers, since a previous attempt may still
+	// read its body when the next attempt starts.
+	if ra, ok := data.(io.ReaderAt); ok {
+		getBody := func() (io.ReadCloser, error) {
+			return io.NopCloser(io.NewSectionReader(ra, offset, size)), nil
+		}
+		body, _ := getBody()
+		return body, getBody, nil
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind upload: %w", err)
+		}
+		return io.NopCloser(data), nil
+	}
+	body, _ := getBody()
+	return body, getBody, nil
+}
+
+func (o UploadOptions) bundleOptions(initialRun bool) BundleOptions {
+	return BundleOptions{
+		InitialRun: initialRun,
+		Reports:    o.Reports,
+		Include:    o.Include,
+		Exclude:    o.Exclude,
+		MaxReports: o.MaxReports,
+
+		InvalidReports: o.InvalidReports,
+	}
+}
 
+// uploadApi returns the API client of the TestLab server in the env vars.
+func uploadApi(l *slog.Logger, osEnv map[string]string, hc *http.Client) (*api, error) {
 	server := osEnv["TESTLAB_HOST"]
 	if server == "" {
 		server = "https://eu.testlab.tools"
@@ -139,24 +282,39 @@ func Upload(l *slog.Logger, osEnv map[string]string, o UploadOptions) error {
 
 	apiKey := osEnv["TESTLAB_KEY"]
 	if apiKey == "" {
-		return fmt.Errorf("env var TESTLAB_KEY is required")
+		return nil, fmt.Errorf("env var TESTLAB_KEY is required")
 	}
 
 	l.Info("upload run", "server", server, "apiKey", mask(apiKey))
 
-	collector, err := NewCollector(l, o.Repo, osEnv)
+	return newApi(l, hc, server, apiKey)
+}
+
+func Upload(l *slog.Logger, osEnv map[string]string, o UploadOptions) error {
+	if o.Output != "" {
+		return saveBundle(l, osEnv, o)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	api, err := uploadApi(l, osEnv, o.Client)
 	if err != nil {
 		return err
 	}
 
-	env := collector.Env()
-	l.Debug("collected env vars", "env", env)
+	if o.From != "" {
+		return replayBundle(ctx, l, api, o.From, o.PartSize)
+	}
 
-	api, err := newApi(l, o.Client, server, apiKey)
+	collector, err := NewCollector(l, o.Repo, osEnv)
 	if err != nil {
 		return err
 	}
 
+	env := collector.Env()
+	l.Debug("collected env vars", "env", env)
+
 	runReq := env.RunRequest()
 	runReq.Started = o.Started
 
@@ -167,24 +325,85 @@ func Upload(l *slog.Logger, osEnv map[string]string, o UploadOptions) error {
 
 	l.Info("created run", "runId", run.Id, "created", created, "reports", o.Reports)
 
-	var data bytes.Buffer
-	if err := collector.Bundle(BundleOptions{
-		InitialRun: created,
-		ReportsDir: o.Reports,
-		MaxReports: o.MaxReports,
-	}, &data); err != nil {
+	upload, cleanup, err := bundleUpload(collector, o.bundleOptions(created), o.PartSize)
+	if err != nil {
 		return fmt.Errorf("failed to bundle: %w", err)
 	}
+	defer cleanup()
 
-	if data.Len() == 0 {
+	if upload.size == 0 {
 		l.Warn("collected tarball is empty. Skip file upload")
 	} else {
-		l.Info("tarball compressed", "size", data.Len())
+		l.Info("tarball compressed", "size", upload.size)
 
-		if err := api.uploadRunFile(ctx, run, &data); err != nil {
+		if err := api.uploadRunFile(ctx, run, upload); err != nil {
 			return fmt.Errorf("failed to upload run: %w", err)
 		}
 	}
 
 	return nil
 }
+
+// bundleUpload returns the upload of the bundle and a function to release
+// it. The bundle is compressed while it is uploaded, so it is neither kept in
+// memory nor on disk. Since pre-signed URLs reject uploads without a
+// Content-Length, the bundle is compressed once before to get its size.
+//
+// Multipart uploads read their parts concurrently, so the bundle is spooled
+// into a temporary file instead if partSize is set.
+func bundleUpload(c *Collector, o BundleOptions, partSize int64) (fileUpload, func(), error) {
+	if partSize > 0 {
+		data, size, err := spoolBundle(c, o)
+		if err != nil {
+			return fileUpload{}, nil, err
+		}
+		upload := fileUpload{data: data, size: size, partSize: partSize}
+		return upload, func() { removeFile(data) }, nil
+	}
+
+	size := &countWriter{w: io.Discard}
+	if err := c.Bundle(o, size); err != nil {
+		return fileUpload{}, nil, err
+	}
+
+	open := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(c.Bundle(o, pw))
+		}()
+		return pr, nil
+	}
+
+	return fileUpload{size: size.n, open: open}, func() {}, nil
+}
+
+// spoolBundle writes the bundle into a temporary file and returns the file
+// rewound to the start.
+func spoolBundle(c *Collector, o BundleOptions) (*os.File, int64, error) {
+	f, err := os.CreateTemp("", "record-*.tar.zst")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create bundle file: %w", err)
+	}
+
+	if err := c.Bundle(o, f); err != nil {
+		removeFile(f)
+		return nil, 0, err
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeFile(f)
+		return nil, 0, fmt.Errorf("failed to rewind bundle file: %w", err)
+	}
+
+	return f, size, nil
+}
+
+// removeFile closes and removes a temporary file.
+func removeFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -30,9 +30,10 @@ func TestUploadFromGithub(t *testing.T) {
 		{
 			name: "default",
 			options: UploadOptions{
-				Reports: "testdata/basic/reports",
+				Reports: []string{"testdata/basic/reports"},
 			},
 			expected: map[string]string{
+				BundleManifestFileName:             generated,
 				"testdata/basic/reports/e2e-1.xml": "reports/1.xml",
 				"testdata/basic/reports/e2e-2.xml": "reports/2.xml",
 			},
@@ -40,20 +41,121 @@ func TestUploadFromGithub(t *testing.T) {
 		{
 			name: "github",
 			options: UploadOptions{
-				Reports: "testdata/github/reports",
+				Reports: []string{"testdata/github/reports"},
 				Repo:    "testdata/github/repo",
 			},
 			expected: map[string]string{
+				BundleManifestFileName:                    generated,
 				"testdata/github/reports/e2e-1.xml":       "reports/1.xml",
 				"testdata/github/reports/e2e-2.xml":       "reports/2.xml",
 				"testdata/github/repo/.github/CODEOWNERS": "CODEOWNERS",
 				GitSummaryFileName:                        generated,
 			},
 		},
+		{
+			name: "multiple paths",
+			options: UploadOptions{
+				Reports: []string{
+					"testdata/basic/reports/e2e-2.xml",
+					"testdata/github/reports",
+					"testdata/basic/reports/*.xml",
+				},
+			},
+			expected: map[string]string{
+				BundleManifestFileName:              generated,
+				"testdata/basic/reports/e2e-2.xml":  "reports/1.xml",
+				"testdata/github/reports/e2e-1.xml": "reports/2.xml",
+				"testdata/github/reports/e2e-2.xml": "reports/3.xml",
+				"testdata/basic/reports/e2e-1.xml":  "reports/4.xml",
+			},
+		},
+		{
+			name: "glob exclude",
+			options: UploadOptions{
+				Reports: []string{"testdata/**/reports/*.xml"},
+				Exclude: []string{"**/github/**", "**/invalid/**"},
+			},
+			expected: map[string]string{
+				BundleManifestFileName:             generated,
+				"testdata/basic/reports/e2e-1.xml": "reports/1.xml",
+				"testdata/basic/reports/e2e-2.xml": "reports/2.xml",
+			},
+		},
+		{
+			name: "glob include",
+			options: UploadOptions{
+				Reports: []string{
+					"testdata/basic/reports",
+					"testdata/github/reports",
+				},
+				Include: []string{"**/e2e-1.xml"},
+			},
+			expected: map[string]string{
+				BundleManifestFileName:              generated,
+				"testdata/basic/reports/e2e-1.xml":  "reports/1.xml",
+				"testdata/github/reports/e2e-1.xml": "reports/2.xml",
+			},
+		},
+		{
+			name: "exclude file name",
+			options: UploadOptions{
+				Reports:        []string{"testdata/invalid/reports"},
+				Exclude:        []string{"*.log", "e2e-2.xml"},
+				InvalidReports: InvalidReportsKeep,
+			},
+			expected: map[string]string{
+				BundleManifestFileName:               generated,
+				"testdata/invalid/reports/e2e-1.xml": "reports/1.xml",
+			},
+		},
+		{
+			name: "include relative path",
+			options: UploadOptions{
+				Reports: []string{"testdata"},
+				Include: []string{"basic/reports/*.xml"},
+			},
+			expected: map[string]string{
+				BundleManifestFileName:             generated,
+				"testdata/basic/reports/e2e-1.xml": "reports/1.xml",
+				"testdata/basic/reports/e2e-2.xml": "reports/2.xml",
+			},
+		},
+		{
+			name: "invalid reports omitted",
+			options: UploadOptions{
+				Reports: []string{"testdata/invalid/reports"},
+			},
+			expected: map[string]string{
+				BundleManifestFileName:                generated,
+				"testdata/invalid/reports/e2e-1.xml":  "reports/1.xml",
+				"testdata/invalid/reports/output.log": "reports/2.log",
+			},
+		},
+		{
+			name: "invalid reports kept",
+			options: UploadOptions{
+				Reports:        []string{"testdata/invalid/reports"},
+				InvalidReports: InvalidReportsKeep,
+			},
+			expected: map[string]string{
+				BundleManifestFileName:                generated,
+				"testdata/invalid/reports/e2e-1.xml":  "reports/1.xml",
+				"testdata/invalid/reports/e2e-2.xml":  "reports/2.xml",
+				"testdata/invalid/reports/output.log": "reports/3.log",
+			},
+		},
+		{
+			name: "invalid reports fail",
+			options: UploadOptions{
+				Reports:        []string{"testdata/invalid/reports"},
+				InvalidReports: InvalidReportsFail,
+			},
+			err: `invalid report "testdata/invalid/reports/e2e-2.xml"`,
+		},
 		{
 			name: "empty reports",
 			options: UploadOptions{
-				Reports: "testdata/unknown/reports",
+				Reports: []string{"testdata/unknown/reports"},
 			},
 			expected: map[string]string{},
 		},
@@ -65,7 +167,7 @@ func TestUploadFromGithub(t *testing.T) {
 		{
 			name: "too many reports",
 			options: UploadOptions{
-				Reports:    "testdata/basic/reports",
+				Reports:    []string{"testdata/basic/reports"},
 				MaxReports: 1,
 			},
 			err: "too many files (2 > 1) found",
@@ -142,7 +244,7 @@ func TestUploadWithRetryOnServerError(t *testing.T) {
 	}
 
 	options := UploadOptions{
-		Reports: "testdata/github/reports",
+		Reports: []string{"testdata/github/reports"},
 		Repo:    "testdata/github/repo",
 		Client:  &http.Client{Transport: rt},
 	}
@@ -157,6 +259,53 @@ func TestUploadWithRetryOnServerError(t *testing.T) {
 	}
 }
 
+func TestUploadStreamRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	l := slogt.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	// Fail the first upload after the whole bundle was sent, so the retry
+	// has to bundle the reports again.
+	var sizes []int64
+	putFile := srv.Handlers.PutS3File
+	srv.Handlers.PutS3File = func(w http.ResponseWriter, r *http.Request) {
+		sizes = append(sizes, r.ContentLength)
+		if len(sizes) == 1 {
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		putFile(w, r)
+	}
+
+	rt := &retryTransport{
+		maxRetries: 3,
+		log:        l,
+		sleep:      sleepNoop(t),
+	}
+
+	err := Upload(l, srv.Env, UploadOptions{
+		Reports: []string{"testdata/github/reports"},
+		Repo:    "testdata/github/repo",
+		Client:  &http.Client{Transport: rt},
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	if assert.Len(sizes, 2) && assert.Len(srv.Files, 1) {
+		assert.Equal(sizes[0], sizes[1])
+		assert.Equal(sizes[1], int64(len(srv.Files[0])))
+
+		files, err := srv.ExtractTar(0)
+		assert.NoError(err)
+		assert.Contains(files, "reports/1.xml")
+	}
+}
+
 func mustReadFiles(expected map[string]string, actual map[string][]byte) map[string][]byte {
 	contents := make(map[string][]byte)
 	for file, key := range expected {
@@ -188,9 +337,10 @@ func TestUploadSkipsFilesForSameRun(t *testing.T) {
 		{
 			name: "default",
 			options: UploadOptions{
-				Reports: "testdata/basic/reports",
+				Reports: []string{"testdata/basic/reports"},
 			},
 			expected: map[string]string{
+				BundleManifestFileName:             generated,
 				"testdata/basic/reports/e2e-1.xml": "reports/1.xml",
 				"testdata/basic/reports/e2e-2.xml": "reports/2.xml",
 				// git.json is skipped for non-initial runs.
@@ -199,10 +349,11 @@ func TestUploadSkipsFilesForSameRun(t *testing.T) {
 		{
 			name: "github",
 			options: UploadOptions{
-				Reports: "testdata/github/reports",
+				Reports: []string{"testdata/github/reports"},
 				Repo:    "testdata/github/repo",
 			},
 			expected: map[string]string{
+				BundleManifestFileName:              generated,
 				"testdata/github/reports/e2e-1.xml": "reports/1.xml",
 				"testdata/github/reports/e2e-2.xml": "reports/2.xml",
 				// CODEOWNERS and git.json are skipped for non-initial runs.
@@ -245,7 +396,7 @@ func TestUploadAddsGitTags(t *testing.T) {
 		{
 			name: "default",
 			options: UploadOptions{
-				Reports: "testdata/basic/reports",
+				Reports: []string{"testdata/basic/reports"},
 				Repo:    "testdata/basic/repo",
 			},
 			tags: nil,
@@ -253,7 +404,7 @@ func TestUploadAddsGitTags(t *testing.T) {
 		{
 			name: "github",
 			options: UploadOptions{
-				Reports: "testdata/github/reports",
+				Reports: []string{"testdata/github/reports"},
 				Repo:    "testdata/github/repo",
 			},
 			tags: []string{"1.0.2"},
@@ -261,7 +412,7 @@ func TestUploadAddsGitTags(t *testing.T) {
 		{
 			name: "feature",
 			options: UploadOptions{
-				Reports: "testdata/feature/reports",
+				Reports: []string{"testdata/feature/reports"},
 				Repo:    "testdata/feature/repo",
 			},
 			tags: []string{"1.0.2", "2.my-feature.3"},
@@ -312,7 +463,7 @@ func TestUploadGitSummary(t *testing.T) {
 		{
 			name: "github",
 			options: UploadOptions{
-				Reports: "testdata/github/reports",
+				Reports: []string{"testdata/github/reports"},
 				Repo:    "testdata/github/repo",
 			},
 			expected: true,
@@ -320,7 +471,7 @@ func TestUploadGitSummary(t *testing.T) {
 		{
 			name: "feature",
 			options: UploadOptions{
-				Reports: "testdata/feature/reports",
+				Reports: []string{"testdata/feature/reports"},
 				Repo:    "testdata/feature/repo",
 			},
 			expected: false,
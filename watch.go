@@ -0,0 +1,299 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/testlabtools/record/client"
+)
+
+// DefaultWatchInterval is the poll interval of the report paths in watch mode.
+const DefaultWatchInterval = 10 * time.Second
+
+type WatchOptions struct {
+	UploadOptions
+
+	// Command is the test command. Its reports are uploaded while it runs
+	// and once more after it exits.
+	Command []string
+
+	WorkDir string
+
+	// Interval is the poll interval of the report paths. If zero,
+	// DefaultWatchInterval is used.
+	Interval time.Duration
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// reportStat is used to detect reports that are still being written.
+type reportStat struct {
+	size     int64
+	modified time.Time
+}
+
+// watcher uploads new report files as run files of the same run.
+type watcher struct {
+	log *slog.Logger
+	o   WatchOptions
+
+	collector *Collector
+	api       *api
+	run       *client.CIRunResponse
+
+	// initial is true until the first run file of a created run is uploaded.
+	initial bool
+
+	// quiet is the collector used to poll the report paths without warnings
+	// for missing reports.
+	quiet *Collector
+
+	// uploaded are the stats of the uploaded reports, which are used to warn
+	// about reports that changed after they were uploaded.
+	uploaded map[string]reportStat
+	stats    map[string]reportStat
+}
+
+// Watch runs the test command and uploads its finished report files in
+// batches while the command runs. Each batch is uploaded as a new run file of
+// the same run. After the command exits, the remaining reports are uploaded.
+// It returns the exit code of the test command. Like Run, upload failures are
+// logged, so they do not change the exit code.
+//
+// Reports must not change after they were uploaded, since each upload adds
+// their tests to the run. Changed reports are logged and not uploaded again.
+func Watch(l *slog.Logger, osEnv map[string]string, o WatchOptions) (int, error) {
+	if len(o.Command) == 0 {
+		return 0, fmt.Errorf("test command is required")
+	}
+
+	if o.Interval == 0 {
+		o.Interval = DefaultWatchInterval
+	}
+
+	started := time.Now().UTC()
+	if o.Started != nil {
+		started = *o.Started
+	}
+	o.Started = &started
+
+	w, err := newWatcher(l, osEnv, o)
+	if err != nil {
+		return 0, err
+	}
+
+	l.Info("run test command", "args", o.Command, "interval", o.Interval)
+
+	cmd := testCommand(o.Command, o.WorkDir, o.Stdout, o.Stderr)
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to run test command: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.upload(false); err != nil {
+				l.Error("failed to upload test reports", "err", err)
+			}
+
+		case err := <-done:
+			code, err := testCommandExit(l, err, started)
+			if err != nil {
+				return 0, err
+			}
+
+			if err := w.upload(true); err != nil {
+				l.Error("failed to upload test reports", "err", err)
+			}
+
+			return code, nil
+		}
+	}
+}
+
+// newWatcher creates the run of the watched reports.
+func newWatcher(l *slog.Logger, osEnv map[string]string, o WatchOptions) (*watcher, error) {
+	if o.MaxReports == 0 {
+		o.MaxReports = DefaulMaxReports
+	}
+
+	api, err := uploadApi(l, osEnv, o.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	collector, err := NewCollector(l, o.Repo, osEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	runReq := collector.Env().RunRequest()
+	runReq.Started = o.Started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	run, created, err := api.createRun(ctx, runReq)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run: %w", err)
+	}
+
+	l.Info("created run", "runId", run.Id, "created", created, "reports", o.Reports)
+
+	quiet := *collector
+	quiet.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &watcher{
+		log:       l,
+		o:         o,
+		collector: collector,
+		api:       api,
+		run:       run,
+		initial:   created,
+		quiet:     &quiet,
+		uploaded:  make(map[string]reportStat),
+		stats:     make(map[string]reportStat),
+	}, nil
+}
+
+// pending returns the report files that are not uploaded yet. Unless final, a
+// report is only returned if it did not change since the last poll and it is
+// valid JUnit XML, since the test runner may still be writing it.
+func (w *watcher) pending(final bool) ([]string, error) {
+	o := BundleOptions{
+		Reports: w.o.Reports,
+		Include: w.o.Include,
+		Exclude: w.o.Exclude,
+	}
+
+	c := w.quiet
+	if final {
+		c = w.collector
+	}
+
+	paths, err := c.findReports(o, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+
+	for _, path := range paths {
+		key := filepath.Clean(path)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		stat := reportStat{size: info.Size(), modified: info.ModTime()}
+		last, ok := w.stats[key]
+		w.stats[key] = stat
+
+		if uploaded, ok := w.uploaded[key]; ok {
+			if uploaded != stat {
+				w.log.Warn("report changed after it was uploaded, skip it", "file", path)
+				w.uploaded[key] = stat
+			}
+			continue
+		}
+
+		if !final {
+			if !ok || last != stat {
+				continue
+			}
+
+			if _, _, err := scanReport(path); err != nil {
+				continue
+			}
+		}
+
+		pending = append(pending, path)
+	}
+
+	return pending, nil
+}
+
+// upload uploads the pending reports in batches of at most MaxReports files.
+// Reports of failed batches stay pending for the next poll.
+func (w *watcher) upload(final bool) error {
+	pending, err := w.pending(final)
+	if err != nil {
+		return fmt.Errorf("failed to find reports (%q): %w", w.o.Reports, err)
+	}
+
+	if len(pending) == 0 {
+		w.log.Debug("no new reports found", "final", final)
+		return nil
+	}
+
+	for len(pending) > 0 {
+		n := min(len(pending), w.o.MaxReports)
+		batch := pending[:n]
+		pending = pending[n:]
+
+		if err := w.uploadBatch(batch); err != nil {
+			return err
+		}
+
+		for _, path := range batch {
+			key := filepath.Clean(path)
+			w.uploaded[key] = w.stats[key]
+		}
+	}
+
+	return nil
+}
+
+func (w *watcher) uploadBatch(paths []string) error {
+	w.log.Info("upload report batch", "files", len(paths), "initial", w.initial)
+
+	o := BundleOptions{
+		InitialRun:     w.initial,
+		Reports:        paths,
+		InvalidReports: w.o.InvalidReports,
+		MaxReports:     len(paths),
+	}
+
+	data, size, err := spoolBundle(w.collector, o)
+	if err != nil {
+		return fmt.Errorf("failed to bundle: %w", err)
+	}
+	defer removeFile(data)
+
+	if size == 0 {
+		// All reports of the batch were omitted.
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	upload := fileUpload{
+		data:     data,
+		size:     size,
+		partSize: w.o.PartSize,
+	}
+	if err := w.api.uploadRunFile(ctx, w.run, upload); err != nil {
+		return fmt.Errorf("failed to upload run: %w", err)
+	}
+
+	w.initial = false
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/testlabtools/record/client"
+	"github.com/testlabtools/record/fake"
+)
+
+// copyReport copies the test report into dir and sets its modification time.
+func copyReport(t *testing.T, report, file string, modified time.Time) {
+	content, err := os.ReadFile(filepath.Join("testdata/github/reports", report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchUploads(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	w, err := newWatcher(l, srv.Env, WatchOptions{
+		UploadOptions: UploadOptions{
+			Repo:    "testdata/github/repo",
+			Reports: []string{dir},
+		},
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	modified := time.Now().Add(-time.Minute)
+
+	// A report is uploaded once it did not change since the last poll.
+	copyReport(t, "e2e-1.xml", filepath.Join(dir, "1.xml"), modified)
+	assert.NoError(w.upload(false))
+	assert.Empty(srv.Files)
+	assert.NoError(w.upload(false))
+	assert.Len(srv.Files, 1)
+
+	copyReport(t, "e2e-2.xml", filepath.Join(dir, "2.xml"), modified)
+	assert.NoError(w.upload(false))
+	assert.NoError(w.upload(false))
+	assert.NoError(w.upload(true))
+
+	assert.Len(srv.Runs, 1)
+	if !assert.Len(srv.Files, 2) {
+		return
+	}
+
+	expected := []struct {
+		path    string
+		initial bool
+	}{
+		{filepath.Join(dir, "1.xml"), true},
+		{filepath.Join(dir, "2.xml"), false},
+	}
+
+	for i, e := range expected {
+		files, err := srv.ExtractTar(i)
+		if !assert.NoError(err) {
+			return
+		}
+
+		var m BundleManifest
+		if !assert.NoError(json.Unmarshal(files[BundleManifestFileName], &m)) {
+			return
+		}
+		if assert.Len(m.Files, 1) {
+			assert.Equal(filepath.ToSlash(e.path), m.Files[0].Path)
+		}
+
+		if e.initial {
+			assert.Contains(files, "CODEOWNERS")
+			assert.Contains(files, GitSummaryFileName)
+		} else {
+			assert.NotContains(files, "CODEOWNERS")
+			assert.NotContains(files, GitSummaryFileName)
+		}
+	}
+}
+
+func TestWatchSkipsChangedReport(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "1.xml")
+
+	w, err := newWatcher(l, srv.Env, WatchOptions{
+		UploadOptions: UploadOptions{
+			Repo:    "testdata/github/repo",
+			Reports: []string{dir},
+		},
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	modified := time.Now().Add(-time.Minute)
+
+	copyReport(t, "e2e-1.xml", file, modified)
+	assert.NoError(w.upload(false))
+	assert.NoError(w.upload(false))
+
+	// The rewritten report is not uploaded again, since its tests would be
+	// counted twice.
+	copyReport(t, "e2e-2.xml", file, modified.Add(time.Second))
+	assert.NoError(w.upload(false))
+	assert.NoError(w.upload(false))
+	assert.NoError(w.upload(true))
+
+	if assert.Len(srv.Files, 1) {
+		files, err := srv.ExtractTar(0)
+		if !assert.NoError(err) {
+			return
+		}
+
+		content, err := os.ReadFile("testdata/github/reports/e2e-1.xml")
+		if assert.NoError(err) {
+			assert.Equal(content, files["reports/1.xml"])
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	var stdout bytes.Buffer
+
+	code, err := Watch(l, srv.Env, WatchOptions{
+		UploadOptions: UploadOptions{
+			Repo:    "testdata/github/repo",
+			Reports: []string{dir},
+		},
+		Command:  []string{"sh", "-c", `cp testdata/github/reports/e2e-1.xml "$1/1.xml"; echo done; exit 2`, "sh", dir},
+		Interval: time.Hour,
+		Stdout:   &stdout,
+	})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(2, code)
+	assert.Equal("done\n", stdout.String())
+	assert.Len(srv.Files, 1)
+}
+
+func TestWatchSignalExitCode(t *testing.T) {
+	l := slogt.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	code, err := Watch(l, srv.Env, WatchOptions{
+		UploadOptions: UploadOptions{
+			Repo:    "testdata/github/repo",
+			Reports: []string{t.TempDir()},
+		},
+		Command: []string{"sh", "-c", "kill -TERM $$"},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 143, code)
+	}
+}
+
+func TestWatchUploadsUnfinishedReportsOnExit(t *testing.T) {
+	l := slogt.New(t)
+	assert := assert.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	code, err := Watch(l, srv.Env, WatchOptions{
+		UploadOptions: UploadOptions{
+			Repo:    "testdata/github/repo",
+			Reports: []string{dir},
+		},
+		Command:  []string{"cp", "testdata/github/reports/e2e-1.xml", dir},
+		Interval: time.Hour,
+	})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(0, code)
+
+	if assert.Len(srv.Files, 1) {
+		files, err := srv.ExtractTar(0)
+		if assert.NoError(err) {
+			assert.Contains(files, "reports/1.xml")
+			assert.Contains(files, "CODEOWNERS")
+		}
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	l := slogt.New(t)
+
+	srv := fake.NewServer(t, l, client.Github)
+	defer srv.Close()
+
+	_, err := Watch(l, srv.Env, WatchOptions{})
+	assert.ErrorContains(t, err, "test command is required")
+
+	_, err = Watch(l, srv.Env, WatchOptions{
+		UploadOptions: UploadOptions{
+			Repo: "testdata/github/repo",
+		},
+		Command: []string{"./unknown-test-command"},
+	})
+	assert.ErrorContains(t, err, "failed to run test command")
+}
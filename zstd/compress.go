@@ -41,3 +41,23 @@ func Decompress(r io.Reader, w io.Writer) error {
 
 	return nil
 }
+
+// NewWriter returns a writer that compresses data using Zstd into w. The
+// writer must be closed to flush the compressed data.
+func NewWriter(w io.Writer) (io.WriteCloser, error) {
+	z, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Zstd writer: %w", err)
+	}
+	return z, nil
+}
+
+// NewReader returns a reader that decompresses the Zstd data of r. The reader
+// must be closed to release its resources.
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	z, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Zstd reader: %w", err)
+	}
+	return z.IOReadCloser(), nil
+}